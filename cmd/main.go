@@ -1,59 +1,509 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	stderrors "errors"
+	"flag"
 	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	// Time zones are validated against the IANA database, which is not
+	// installed in the alpine image.
+	_ "time/tzdata"
 
 	"github.com/SawitProRecruitment/UserService/config"
 	"github.com/SawitProRecruitment/UserService/config/env"
+	"github.com/SawitProRecruitment/UserService/config/source"
 	"github.com/SawitProRecruitment/UserService/generated"
 	"github.com/SawitProRecruitment/UserService/handler"
+	"github.com/SawitProRecruitment/UserService/handler/middleware"
+	"github.com/SawitProRecruitment/UserService/lib/blob"
+	"github.com/SawitProRecruitment/UserService/lib/cache"
 	"github.com/SawitProRecruitment/UserService/lib/errors"
+	"github.com/SawitProRecruitment/UserService/lib/jwt"
+	"github.com/SawitProRecruitment/UserService/lib/logger"
+	"github.com/SawitProRecruitment/UserService/lib/mailer"
+	"github.com/SawitProRecruitment/UserService/lib/metrics"
+	"github.com/SawitProRecruitment/UserService/lib/password"
+	"github.com/SawitProRecruitment/UserService/lib/phone"
+	"github.com/SawitProRecruitment/UserService/lib/telemetry"
+	"github.com/SawitProRecruitment/UserService/lib/tracing"
 	"github.com/SawitProRecruitment/UserService/lib/validator"
+	"github.com/SawitProRecruitment/UserService/migrations"
 	"github.com/SawitProRecruitment/UserService/repository"
 	"github.com/SawitProRecruitment/UserService/service"
 
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
 	_ "github.com/lib/pq"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"golang.org/x/crypto/bcrypt"
+	_ "modernc.org/sqlite"
 )
 
-var cfg *config.Config
-
-func init() {
-	godotenv.Load(".env")
-	envConfig := env.New()
-	config.Init(envConfig)
-	cfg = config.Load()
-}
-
 // @contact.name Ronaldo Tantra
 // @contact.email ronaldotantra@gmail.com
 func main() {
+	godotenv.Load(".env")
+	storage := flag.String("storage", "database", "where users are stored: database, the one of DATABASE_URL, or memory to run without a database")
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML configuration file, overridden by the environment, itself overridden by flags")
+	flags := source.Flags(flag.CommandLine, env.Keys)
+	flag.Parse()
+
+	values, err := loadConfig(*configFile, flags, *storage)
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(flag.Args(), values, err))
+	}
+	if err != nil {
+		exit(configError{err})
+	}
+	cfg := config.New(values)
+
+	log := logger.New(os.Stdout, cfg.LogLevel())
+	slog.SetDefault(log)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Init(ctx, telemetry.Options{
+		ServiceName:  cfg.ApplicationName(),
+		Exporter:     cfg.TracesExporter(),
+		OtlpEndpoint: cfg.OtlpEndpoint(),
+	})
+	if err != nil {
+		exit(configError{err})
+	}
+	shutdownMetrics, err := metrics.Init(ctx, telemetry.Options{
+		ServiceName:  cfg.ApplicationName(),
+		Exporter:     cfg.MetricsExporter(),
+		OtlpEndpoint: cfg.OtlpEndpoint(),
+	})
+	if err != nil {
+		exit(configError{err})
+	}
+
+	phoneRules, err := phone.ParseCountryRules(cfg.PhoneCountryCodes())
+	if err != nil {
+		exit(configError{err})
+	}
+	phoneParser := phone.NewParser(phone.Options{
+		DefaultCountryCode: cfg.PhoneDefaultCountryCode(),
+		Countries:          phoneRules,
+	})
+
 	e := echo.New()
-	e.HTTPErrorHandler = errors.CustomHTTPErrorHandler
-	e.Validator = validator.NewValidator()
+	e.HTTPErrorHandler = errors.NewHTTPErrorHandler(errors.HTTPErrorHandlerOptions{
+		ProblemTypeBase: cfg.ProblemTypeBaseURL(),
+	})
+	e.Validator = validator.NewValidatorWithOptions(validator.NewValidatorOptions{
+		PhoneParser: phoneParser,
+	})
+	e.Use(otelecho.Middleware(cfg.ApplicationName()))
+	e.Use(middleware.TraceResponse)
+	e.Use(middleware.RequestLogger(log))
+	e.Use(middleware.ReadYourWrites)
 
-	var server generated.ServerInterface = newServer(cfg)
+	server, err := newServer(ctx, cfg, phoneParser, *storage)
+	if err != nil {
+		exit(err)
+	}
 	generated.RegisterHandlers(e, server)
 
-	e.Logger.Fatal(e.Start(fmt.Sprint(":", cfg.AppPort())))
+	go func() {
+		if err := e.Start(fmt.Sprint(":", cfg.AppPort())); err != nil && err != http.ErrServerClosed {
+			log.Error("server stopped", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		log.Error("failed to shutdown server", slog.String("error", err.Error()))
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Error("failed to shutdown tracing", slog.String("error", err.Error()))
+	}
+	if err := shutdownMetrics(shutdownCtx); err != nil {
+		log.Error("failed to shutdown metrics", slog.String("error", err.Error()))
+	}
+}
+
+// configError is a mistake in the configuration, as opposed to a failure of
+// what it points to, such as an unreachable database.
+type configError struct {
+	error
 }
 
-func newServer(config *config.Config) *handler.Server {
-	dbDsn := config.DatabaseUrl()
-	db, err := sql.Open("postgres", dbDsn)
+func (err configError) Unwrap() error {
+	return err.error
+}
+
+// exit reports why the service cannot start and exits, with 2 when the
+// configuration is invalid and 1 otherwise.
+func exit(err error) {
+	if stderrors.As(err, new(configError)) {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	fmt.Fprintf(os.Stderr, "failed to start: %v\n", err)
+	os.Exit(1)
+}
+
+// loadConfig layers the flags over the environment over the file, and
+// validates the result, values that do not parse first and then those the
+// service cannot run with. The values are nil when the file cannot be read.
+func loadConfig(file string, flags source.Source, storage string) (*env.Env, error) {
+	sources := []source.Source{flags, source.Env()}
+	if file != "" {
+		fileSource, err := source.File(file, env.Keys)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, fileSource)
+	}
+	values := env.NewFromSource(source.Layers(sources...))
+	return values, stderrors.Join(values.Validate(), validateConfig(config.New(values), storage))
+}
+
+// runCommand runs the command of the arguments left after the flags in place
+// of the server, and returns the exit code.
+func runCommand(args []string, values *env.Env, configErr error) int {
+	switch strings.Join(args, " ") {
+	case "config print":
+		// Print what is known even when invalid, it helps finding out why.
+		if values != nil {
+			if err := values.Print(os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+		}
+		if configErr != nil {
+			fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", configErr)
+			return 2
+		}
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q, want \"config print\"\n", strings.Join(args, " "))
+		return 2
+	}
+}
+
+func newServer(ctx context.Context, config *config.Config, phoneParser *phone.Parser, storage string) (*handler.Server, error) {
+	repo, err := newRepository(ctx, config, storage)
+	if err != nil {
+		return nil, err
+	}
+	repo, err = newCachedRepository(config, repo)
+	if err != nil {
+		return nil, configError{err}
+	}
+	mailer, err := newMailer(config)
+	if err != nil {
+		return nil, configError{err}
+	}
+	passwordHasher, err := newPasswordHasher(config)
+	if err != nil {
+		return nil, configError{err}
+	}
+	passwordPolicy, err := newPasswordPolicy(config)
+	if err != nil {
+		return nil, configError{err}
+	}
+	avatarStore, err := newAvatarStore(config)
 	if err != nil {
-		panic(err)
+		return nil, configError{err}
 	}
-	var repo repository.RepositoryInterface = repository.NewRepository(repository.NewRepositoryOptions{
-		Db: db,
+	tokenService := jwt.NewTokenService(jwt.Options{
+		PrivateKey: config.JwtPrivateKey,
+		Issuer:     config.ApplicationName(),
 	})
 	var service service.ServiceInterface = service.NewService(service.NewServiceOption{
-		UserRepository: repo,
+		UserRepository:       repo,
+		TokenService:         tokenService,
+		PhoneParser:          phoneParser,
+		PasswordHasher:       passwordHasher,
+		PasswordPolicy:       passwordPolicy,
+		PasswordHistorySize:  config.PasswordHistorySize(),
+		Mailer:               mailer,
+		EmailVerificationURL: config.EmailVerificationURL(),
+		PasswordResetURL:     config.PasswordResetURL(),
+		AvatarStore:          avatarStore,
+		AvatarMaxSize:        int64(config.AvatarMaxSize()),
 	})
 	opts := handler.NewServerOptions{
-		Service: service,
+		Service:       service,
+		TokenService:  tokenService,
+		AvatarMaxSize: int64(config.AvatarMaxSize()),
+	}
+	return handler.NewServer(opts), nil
+}
+
+func newRepository(ctx context.Context, config *config.Config, storage string) (repository.RepositoryInterface, error) {
+	switch storage {
+	case "database":
+		database, err := repository.ParseDatabaseURL(config.DatabaseUrl())
+		if err != nil {
+			return nil, configError{err}
+		}
+		opts, queryTimeout, err := newOpenOptions(config)
+		if err != nil {
+			return nil, configError{err}
+		}
+		primaryOpts := opts
+		primaryOpts.ReloadDSN = reloadDSN(database, config.DatabaseUrl)
+		db, err := repository.Open(ctx, database, primaryOpts)
+		if err != nil {
+			return nil, err
+		}
+		migrate := migrations.Up
+		if database.Dialect == repository.SQLite {
+			migrate = migrations.UpSQLite
+		}
+		if err := migrate(ctx, db); err != nil {
+			return nil, err
+		}
+		replicas, err := openReplicas(ctx, config, database, opts)
+		if err != nil {
+			return nil, err
+		}
+		return repository.NewRepository(repository.NewRepositoryOptions{
+			Db:           db,
+			Dialect:      database.Dialect,
+			Replicas:     replicas,
+			QueryTimeout: queryTimeout,
+		}), nil
+	case "memory":
+		slog.Warn("users are stored in memory and lost on exit")
+		return repository.NewMemoryRepository(repository.NewMemoryRepositoryOptions{}), nil
+	default:
+		return nil, configError{fmt.Errorf("unknown storage %q", storage)}
+	}
+}
+
+func newCachedRepository(config *config.Config, repo repository.RepositoryInterface) (repository.RepositoryInterface, error) {
+	switch config.UserCache() {
+	case "none":
+		return repo, nil
+	case "memory":
+		ttl, err := userCacheTTL(config)
+		if err != nil {
+			return nil, err
+		}
+		return repository.NewCachedRepository(repository.NewCachedRepositoryOptions{
+			Repository: repo,
+			Cache:      cache.NewLRU(cache.LRUOptions{Size: config.UserCacheSize()}),
+			TTL:        ttl,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown user cache %q", config.UserCache())
+	}
+}
+
+// userCacheTTL returns the TTL of the memory cache, failing on a TTL or a
+// size that is not positive.
+func userCacheTTL(config *config.Config) (time.Duration, error) {
+	var errs []error
+	ttl, err := time.ParseDuration(config.UserCacheTTL())
+	if err != nil || ttl <= 0 {
+		errs = append(errs, fmt.Errorf("%s: invalid duration %q, want a positive one such as 1m", env.UserCacheTTL, config.UserCacheTTL()))
+	}
+	if config.UserCacheSize() <= 0 {
+		errs = append(errs, fmt.Errorf("%s: %d is not positive", env.UserCacheSize, config.UserCacheSize()))
+	}
+	return ttl, stderrors.Join(errs...)
+}
+
+// openReplicas opens the read replicas of DATABASE_REPLICA_URLS with the pool of
+// the primary.
+func openReplicas(ctx context.Context, config *config.Config, primary repository.Database, opts repository.OpenOptions) ([]*sql.DB, error) {
+	var replicas []*sql.DB
+	for i, replicaURL := range strings.Split(config.DatabaseReplicaUrls(), ",") {
+		replicaURL = strings.TrimSpace(replicaURL)
+		if replicaURL == "" {
+			continue
+		}
+		database, err := repository.ParseDatabaseURL(replicaURL)
+		if err != nil {
+			return nil, configError{fmt.Errorf("DATABASE_REPLICA_URLS #%d: %w", i+1, err)}
+		}
+		if database.Dialect != repository.Postgres || primary.Dialect != repository.Postgres {
+			return nil, configError{fmt.Errorf("DATABASE_REPLICA_URLS #%d: read replicas need PostgreSQL", i+1)}
+		}
+		replicaOpts := opts
+		replicaOpts.ReloadDSN = reloadDSN(database, func() string {
+			if urls := strings.Split(config.DatabaseReplicaUrls(), ","); i < len(urls) {
+				return strings.TrimSpace(urls[i])
+			}
+			return replicaURL
+		})
+		db, err := repository.Open(ctx, database, replicaOpts)
+		if err != nil {
+			return nil, fmt.Errorf("DATABASE_REPLICA_URLS #%d: %w", i+1, err)
+		}
+		replicas = append(replicas, db)
+	}
+	return replicas, nil
+}
+
+// reloadDSN reads the URL of database again for each new connection, so that
+// a rotated password of a secret file is used without a restart.
+func reloadDSN(database repository.Database, databaseURL func() string) func() (string, error) {
+	return func() (string, error) {
+		reloaded, err := repository.ParseDatabaseURL(databaseURL())
+		if err != nil {
+			return "", err
+		}
+		if reloaded.Dialect != database.Dialect {
+			return "", fmt.Errorf("the database url changed from %s to %s, restart to switch", database.Dialect, reloaded.Dialect)
+		}
+		return reloaded.DSN, nil
+	}
+}
+
+// newOpenOptions returns the pool settings and the query timeout, failing on
+// durations that do not parse.
+func newOpenOptions(config *config.Config) (repository.OpenOptions, time.Duration, error) {
+	var errs []error
+	duration := func(name, value string) time.Duration {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: want a duration such as 30s", name, value))
+		}
+		return d
+	}
+	opts := repository.OpenOptions{
+		MaxOpenConns:    config.DbMaxOpenConns(),
+		MaxIdleConns:    config.DbMaxIdleConns(),
+		ConnMaxLifetime: duration("DB_CONN_MAX_LIFETIME", config.DbConnMaxLifetime()),
+		ConnectTimeout:  duration("DB_CONNECT_TIMEOUT", config.DbConnectTimeout()),
+	}
+	queryTimeout := duration("DB_QUERY_TIMEOUT", config.DbQueryTimeout())
+	if queryTimeout < 0 {
+		errs = append(errs, fmt.Errorf("invalid DB_QUERY_TIMEOUT %q: must not be negative", config.DbQueryTimeout()))
+	}
+	return opts, queryTimeout, stderrors.Join(errs...)
+}
+
+func newMailer(config *config.Config) (mailer.Mailer, error) {
+	switch config.Mailer() {
+	case "log":
+		return mailer.NewLogMailer(), nil
+	case "file":
+		return mailer.NewFileMailer(config.MailerFileDir())
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", config.Mailer())
+	}
+}
+
+func newAvatarStore(config *config.Config) (blob.BlobStore, error) {
+	switch config.AvatarStore() {
+	case "local":
+		return blob.NewLocalStore(config.AvatarStoreDir(), config.AvatarBaseURL())
+	default:
+		return nil, fmt.Errorf("unknown avatar store %q", config.AvatarStore())
+	}
+}
+
+func newPasswordHasher(config *config.Config) (password.PasswordHasher, error) {
+	opts, err := passwordHasherOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	return password.NewHasher(opts), nil
+}
+
+// passwordHasherOptions returns the hasher settings, failing on an unknown
+// algorithm and on parameters the hasher would otherwise replace silently.
+func passwordHasherOptions(config *config.Config) (password.Options, error) {
+	var errs []error
+	algorithm, err := password.ParseAlgorithm(config.PasswordHasher())
+	if err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", env.PasswordHasher, err))
+	}
+	if cost := config.BcryptCost(); cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		errs = append(errs, fmt.Errorf("%s: %d is out of range, want %d to %d", env.BcryptCost, cost, bcrypt.MinCost, bcrypt.MaxCost))
+	}
+	for _, param := range []struct {
+		key   string
+		value int
+		max   int
+	}{
+		{env.Argon2idMemory, config.Argon2idMemory(), math.MaxUint32},
+		{env.Argon2idIterations, config.Argon2idIterations(), math.MaxUint32},
+		{env.Argon2idParallelism, config.Argon2idParallelism(), math.MaxUint8},
+	} {
+		if param.value < 1 || param.value > param.max {
+			errs = append(errs, fmt.Errorf("%s: %d is out of range, want 1 to %d", param.key, param.value, param.max))
+		}
+	}
+	return password.Options{
+		Algorithm: algorithm,
+		Argon2id: password.Argon2idParams{
+			Memory:      uint32(config.Argon2idMemory()),
+			Iterations:  uint32(config.Argon2idIterations()),
+			Parallelism: uint8(config.Argon2idParallelism()),
+		},
+		Bcrypt: password.BcryptParams{
+			Cost: config.BcryptCost(),
+		},
+	}, stderrors.Join(errs...)
+}
+
+func newPasswordPolicy(config *config.Config) (*password.Policy, error) {
+	opts, err := passwordPolicyOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	switch config.PasswordBreachedList() {
+	case "none":
+	case "bundled":
+		opts.Breached = password.BundledBreachedList()
+	default:
+		opts.Breached, err = password.LoadBreachedList(config.PasswordBreachedList())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", env.PasswordBreachedList, err)
+		}
+	}
+	return password.NewPolicy(opts), nil
+}
+
+// passwordPolicyOptions returns the policy settings but the breached list,
+// failing on lengths no password can satisfy and on a breached list that is
+// neither a known one nor a file.
+func passwordPolicyOptions(config *config.Config) (password.PolicyOptions, error) {
+	var errs []error
+	minLength, maxLength := config.PasswordMinLength(), config.PasswordMaxLength()
+	if minLength < 1 {
+		errs = append(errs, fmt.Errorf("%s: %d is not positive", env.PasswordMinLength, minLength))
+	}
+	if maxLength < 0 || maxLength > 0 && maxLength < minLength {
+		errs = append(errs, fmt.Errorf("%s: %d is less than %s, want at least %d or 0 for no limit",
+			env.PasswordMaxLength, maxLength, env.PasswordMinLength, minLength))
+	}
+	if config.PasswordHistorySize() < 0 {
+		errs = append(errs, fmt.Errorf("%s: %d is negative", env.PasswordHistorySize, config.PasswordHistorySize()))
+	}
+	if list := config.PasswordBreachedList(); list != "none" && list != "bundled" {
+		if info, err := os.Stat(list); err != nil || info.IsDir() {
+			errs = append(errs, fmt.Errorf("%s: %q is not a file, want none, bundled or the path to a list", env.PasswordBreachedList, list))
+		}
 	}
-	return handler.NewServer(opts)
+	return password.PolicyOptions{
+		MinLength:            minLength,
+		MaxLength:            maxLength,
+		RequireUppercase:     config.PasswordRequireUppercase(),
+		RequireLowercase:     config.PasswordRequireLowercase(),
+		RequireDigit:         config.PasswordRequireDigit(),
+		RequireSpecial:       config.PasswordRequireSpecial(),
+		DisallowPersonalInfo: config.PasswordDisallowPersonalInfo(),
+	}, stderrors.Join(errs...)
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/SawitProRecruitment/UserService/config"
+	"github.com/SawitProRecruitment/UserService/config/env"
+	"github.com/SawitProRecruitment/UserService/lib/phone"
+	"github.com/SawitProRecruitment/UserService/lib/telemetry"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+// validateConfig reports every value the service cannot run with, all at
+// once and before anything is started, opened or migrated.
+func validateConfig(config *config.Config, storage string) error {
+	var errs []error
+	for _, exporter := range []struct{ key, value string }{
+		{env.TracesExporter, config.TracesExporter()},
+		{env.MetricsExporter, config.MetricsExporter()},
+	} {
+		if err := (telemetry.Options{Exporter: exporter.value}).Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", exporter.key, err))
+		}
+	}
+	if base := config.ProblemTypeBaseURL(); base != "" {
+		if u, err := url.Parse(base); err != nil || !u.IsAbs() {
+			errs = append(errs, fmt.Errorf("%s: %q is not an absolute URI", env.ProblemTypeBaseURL, base))
+		}
+	}
+	if _, err := phone.ParseCountryRules(config.PhoneCountryCodes()); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", env.PhoneCountryCodes, err))
+	}
+
+	switch storage {
+	case "database":
+		errs = append(errs, validateDatabase(config))
+	case "memory":
+	default:
+		errs = append(errs, fmt.Errorf("-storage: unknown storage %q, want database or memory", storage))
+	}
+	switch config.UserCache() {
+	case "none":
+	case "memory":
+		_, err := userCacheTTL(config)
+		errs = append(errs, err)
+	default:
+		errs = append(errs, fmt.Errorf("%s: unknown user cache %q, want none or memory", env.UserCache, config.UserCache()))
+	}
+
+	switch config.Mailer() {
+	case "log", "file":
+	default:
+		errs = append(errs, fmt.Errorf("%s: unknown mailer %q, want log or file", env.Mailer, config.Mailer()))
+	}
+	if config.AvatarStore() != "local" {
+		errs = append(errs, fmt.Errorf("%s: unknown avatar store %q, want local", env.AvatarStore, config.AvatarStore()))
+	}
+	if config.AvatarMaxSize() <= 0 {
+		errs = append(errs, fmt.Errorf("%s: %d is not positive", env.AvatarMaxSize, config.AvatarMaxSize()))
+	}
+
+	_, err := passwordHasherOptions(config)
+	errs = append(errs, err)
+	_, err = passwordPolicyOptions(config)
+	errs = append(errs, err)
+	return stderrors.Join(errs...)
+}
+
+// validateDatabase reports the database URLs that cannot be opened and the
+// pool settings that contradict each other.
+func validateDatabase(config *config.Config) error {
+	var errs []error
+	primary, primaryErr := repository.ParseDatabaseURL(config.DatabaseUrl())
+	if primaryErr != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", env.DatabaseUrl, primaryErr))
+	}
+	for i, replicaURL := range strings.Split(config.DatabaseReplicaUrls(), ",") {
+		replicaURL = strings.TrimSpace(replicaURL)
+		if replicaURL == "" {
+			continue
+		}
+		replica, err := repository.ParseDatabaseURL(replicaURL)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s #%d: %w", env.DatabaseReplicaUrls, i+1, err))
+		} else if primaryErr == nil && (replica.Dialect != repository.Postgres || primary.Dialect != repository.Postgres) {
+			errs = append(errs, fmt.Errorf("%s #%d: read replicas need PostgreSQL", env.DatabaseReplicaUrls, i+1))
+		}
+	}
+	opts, _, err := newOpenOptions(config)
+	errs = append(errs, err)
+	if err == nil {
+		errs = append(errs, opts.Validate())
+	}
+	return stderrors.Join(errs...)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/config"
+	"github.com/SawitProRecruitment/UserService/config/env"
+	"github.com/SawitProRecruitment/UserService/config/source"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	t.Parallel()
+
+	newConfig := func(values source.Map) *config.Config {
+		return config.New(env.NewFromSource(values))
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		assert.NoError(t, validateConfig(newConfig(source.Map{}), "database"))
+		assert.NoError(t, validateConfig(newConfig(source.Map{env.UserCache: "memory"}), "memory"))
+	})
+
+	t.Run("reports every invalid key at once", func(t *testing.T) {
+		values := source.Map{
+			env.PhoneCountryCodes:    "zz",
+			env.Mailer:               "smtp",
+			env.AvatarStore:          "s3",
+			env.PasswordHasher:       "md5",
+			env.BcryptCost:           "99",
+			env.Argon2idParallelism:  "0",
+			env.PasswordMinLength:    "0",
+			env.PasswordMaxLength:    "-1",
+			env.PasswordBreachedList: "missing.txt",
+			env.DatabaseUrl:          "mysql://localhost/users",
+			env.DbMaxIdleConns:       "50",
+			env.UserCache:            "memory",
+			env.UserCacheTTL:         "0s",
+			env.UserCacheSize:        "0",
+			env.TracesExporter:       "zipkin",
+			env.ProblemTypeBaseURL:   "/problems/",
+		}
+		err := validateConfig(newConfig(values), "database")
+		if !assert.Error(t, err) {
+			return
+		}
+		for key := range values {
+			if key != env.UserCache && key != env.DbMaxIdleConns {
+				assert.Contains(t, err.Error(), key+":")
+			}
+		}
+		assert.Contains(t, err.Error(), "max idle connections (50) must not exceed max open connections (25)")
+	})
+
+	t.Run("unknown user cache", func(t *testing.T) {
+		err := validateConfig(newConfig(source.Map{env.UserCache: "redis"}), "database")
+		assert.EqualError(t, err, `USER_CACHE: unknown user cache "redis", want none or memory`)
+	})
+
+	t.Run("the database is not checked without one", func(t *testing.T) {
+		assert.NoError(t, validateConfig(newConfig(source.Map{env.DatabaseUrl: "mysql://localhost/users"}), "memory"))
+		assert.EqualError(t, validateConfig(newConfig(source.Map{}), "disk"), `-storage: unknown storage "disk", want database or memory`)
+	})
+
+	t.Run("read replicas need PostgreSQL", func(t *testing.T) {
+		err := validateConfig(newConfig(source.Map{
+			env.DatabaseUrl:         "sqlite://users.db",
+			env.DatabaseReplicaUrls: "postgres://replica/users",
+		}), "database")
+		assert.EqualError(t, err, "DATABASE_REPLICA_URLS #1: read replicas need PostgreSQL")
+	})
+}
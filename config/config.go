@@ -1,19 +1,10 @@
 package config
 
-import (
-	"fmt"
-)
-
 // Config .
 type Config struct {
 	c IConfig
 }
 
-var defaultConfig = new(Config)
-
-// ErrNoInitializationConfig .
-var ErrNoInitializationConfig = fmt.Errorf("config: %s", "No driver is loaded, use blank import to load driver to config.")
-
 // Environment .
 func (c *Config) Environment() string {
 	return c.c.Environment()
@@ -39,15 +30,202 @@ func (c *Config) DatabaseUrl() string {
 	return c.c.DatabaseUrl()
 }
 
-// Init .
-func Init(c IConfig) {
-	defaultConfig.c = c
+// DatabaseReplicaUrls .
+func (c *Config) DatabaseReplicaUrls() string {
+	return c.c.DatabaseReplicaUrls()
+}
+
+// DbMaxOpenConns .
+func (c *Config) DbMaxOpenConns() int {
+	return c.c.DbMaxOpenConns()
+}
+
+// DbMaxIdleConns .
+func (c *Config) DbMaxIdleConns() int {
+	return c.c.DbMaxIdleConns()
+}
+
+// DbConnMaxLifetime .
+func (c *Config) DbConnMaxLifetime() string {
+	return c.c.DbConnMaxLifetime()
+}
+
+// DbConnectTimeout .
+func (c *Config) DbConnectTimeout() string {
+	return c.c.DbConnectTimeout()
+}
+
+// DbQueryTimeout .
+func (c *Config) DbQueryTimeout() string {
+	return c.c.DbQueryTimeout()
+}
+
+// TracesExporter .
+func (c *Config) TracesExporter() string {
+	return c.c.TracesExporter()
+}
+
+// MetricsExporter .
+func (c *Config) MetricsExporter() string {
+	return c.c.MetricsExporter()
+}
+
+// OtlpEndpoint .
+func (c *Config) OtlpEndpoint() string {
+	return c.c.OtlpEndpoint()
+}
+
+// LogLevel .
+func (c *Config) LogLevel() string {
+	return c.c.LogLevel()
+}
+
+// PhoneDefaultCountryCode .
+func (c *Config) PhoneDefaultCountryCode() string {
+	return c.c.PhoneDefaultCountryCode()
+}
+
+// PhoneCountryCodes .
+func (c *Config) PhoneCountryCodes() string {
+	return c.c.PhoneCountryCodes()
+}
+
+// Mailer .
+func (c *Config) Mailer() string {
+	return c.c.Mailer()
+}
+
+// MailerFileDir .
+func (c *Config) MailerFileDir() string {
+	return c.c.MailerFileDir()
+}
+
+// EmailVerificationURL .
+func (c *Config) EmailVerificationURL() string {
+	return c.c.EmailVerificationURL()
+}
+
+// PasswordHasher .
+func (c *Config) PasswordHasher() string {
+	return c.c.PasswordHasher()
+}
+
+// BcryptCost .
+func (c *Config) BcryptCost() int {
+	return c.c.BcryptCost()
+}
+
+// Argon2idMemory .
+func (c *Config) Argon2idMemory() int {
+	return c.c.Argon2idMemory()
+}
+
+// Argon2idIterations .
+func (c *Config) Argon2idIterations() int {
+	return c.c.Argon2idIterations()
+}
+
+// Argon2idParallelism .
+func (c *Config) Argon2idParallelism() int {
+	return c.c.Argon2idParallelism()
+}
+
+// PasswordMinLength .
+func (c *Config) PasswordMinLength() int {
+	return c.c.PasswordMinLength()
+}
+
+// PasswordMaxLength .
+func (c *Config) PasswordMaxLength() int {
+	return c.c.PasswordMaxLength()
+}
+
+// PasswordRequireUppercase .
+func (c *Config) PasswordRequireUppercase() bool {
+	return c.c.PasswordRequireUppercase()
+}
+
+// PasswordRequireLowercase .
+func (c *Config) PasswordRequireLowercase() bool {
+	return c.c.PasswordRequireLowercase()
+}
+
+// PasswordRequireDigit .
+func (c *Config) PasswordRequireDigit() bool {
+	return c.c.PasswordRequireDigit()
+}
+
+// PasswordRequireSpecial .
+func (c *Config) PasswordRequireSpecial() bool {
+	return c.c.PasswordRequireSpecial()
+}
+
+// PasswordDisallowPersonalInfo .
+func (c *Config) PasswordDisallowPersonalInfo() bool {
+	return c.c.PasswordDisallowPersonalInfo()
+}
+
+// PasswordBreachedList .
+func (c *Config) PasswordBreachedList() string {
+	return c.c.PasswordBreachedList()
+}
+
+// PasswordHistorySize .
+func (c *Config) PasswordHistorySize() int {
+	return c.c.PasswordHistorySize()
+}
+
+// PasswordResetURL .
+func (c *Config) PasswordResetURL() string {
+	return c.c.PasswordResetURL()
+}
+
+// AvatarStore .
+func (c *Config) AvatarStore() string {
+	return c.c.AvatarStore()
+}
+
+// AvatarStoreDir .
+func (c *Config) AvatarStoreDir() string {
+	return c.c.AvatarStoreDir()
+}
+
+// AvatarBaseURL .
+func (c *Config) AvatarBaseURL() string {
+	return c.c.AvatarBaseURL()
+}
+
+// AvatarMaxSize .
+func (c *Config) AvatarMaxSize() int {
+	return c.c.AvatarMaxSize()
+}
+
+// UserCache .
+func (c *Config) UserCache() string {
+	return c.c.UserCache()
+}
+
+// UserCacheSize .
+func (c *Config) UserCacheSize() int {
+	return c.c.UserCacheSize()
+}
+
+// UserCacheTTL .
+func (c *Config) UserCacheTTL() string {
+	return c.c.UserCacheTTL()
+}
+
+// ProblemTypeBaseURL .
+func (c *Config) ProblemTypeBaseURL() string {
+	return c.c.ProblemTypeBaseURL()
+}
+
+// Validate .
+func (c *Config) Validate() error {
+	return c.c.Validate()
 }
 
-// Load .
-func Load() *Config {
-	if defaultConfig.c == nil {
-		panic(ErrNoInitializationConfig)
-	}
-	return defaultConfig
+// New wraps the values of c, e.g. an env.Env.
+func New(c IConfig) *Config {
+	return &Config{c: c}
 }
@@ -9,6 +9,146 @@ const (
 	AppPort = "APP_PORT"
 	// JWT_PRIVATE_KEY .
 	JwtPrivateKey = "JWT_PRIVATE_KEY"
+	// JWT_PRIVATE_KEY_FILE .
+	JwtPrivateKeyFile = JwtPrivateKey + fileSuffix
 	// DATABASE_URL .
 	DatabaseUrl = "DATABASE_URL"
+	// DATABASE_URL_FILE .
+	DatabaseUrlFile = DatabaseUrl + fileSuffix
+	// DATABASE_REPLICA_URLS .
+	DatabaseReplicaUrls = "DATABASE_REPLICA_URLS"
+	// DATABASE_REPLICA_URLS_FILE .
+	DatabaseReplicaUrlsFile = DatabaseReplicaUrls + fileSuffix
+	// DB_MAX_OPEN_CONNS .
+	DbMaxOpenConns = "DB_MAX_OPEN_CONNS"
+	// DB_MAX_IDLE_CONNS .
+	DbMaxIdleConns = "DB_MAX_IDLE_CONNS"
+	// DB_CONN_MAX_LIFETIME .
+	DbConnMaxLifetime = "DB_CONN_MAX_LIFETIME"
+	// DB_CONNECT_TIMEOUT .
+	DbConnectTimeout = "DB_CONNECT_TIMEOUT"
+	// DB_QUERY_TIMEOUT .
+	DbQueryTimeout = "DB_QUERY_TIMEOUT"
+	// OTEL_TRACES_EXPORTER .
+	TracesExporter = "OTEL_TRACES_EXPORTER"
+	// OTEL_METRICS_EXPORTER .
+	MetricsExporter = "OTEL_METRICS_EXPORTER"
+	// OTEL_EXPORTER_OTLP_ENDPOINT .
+	OtlpEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	// LOG_LEVEL .
+	LogLevel = "LOG_LEVEL"
+	// PHONE_DEFAULT_COUNTRY_CODE .
+	PhoneDefaultCountryCode = "PHONE_DEFAULT_COUNTRY_CODE"
+	// PHONE_COUNTRY_CODES .
+	PhoneCountryCodes = "PHONE_COUNTRY_CODES"
+	// MAILER .
+	Mailer = "MAILER"
+	// MAILER_FILE_DIR .
+	MailerFileDir = "MAILER_FILE_DIR"
+	// EMAIL_VERIFICATION_URL .
+	EmailVerificationURL = "EMAIL_VERIFICATION_URL"
+	// PASSWORD_HASHER .
+	PasswordHasher = "PASSWORD_HASHER"
+	// BCRYPT_COST .
+	BcryptCost = "BCRYPT_COST"
+	// ARGON2ID_MEMORY .
+	Argon2idMemory = "ARGON2ID_MEMORY"
+	// ARGON2ID_ITERATIONS .
+	Argon2idIterations = "ARGON2ID_ITERATIONS"
+	// ARGON2ID_PARALLELISM .
+	Argon2idParallelism = "ARGON2ID_PARALLELISM"
+	// PASSWORD_MIN_LENGTH .
+	PasswordMinLength = "PASSWORD_MIN_LENGTH"
+	// PASSWORD_MAX_LENGTH .
+	PasswordMaxLength = "PASSWORD_MAX_LENGTH"
+	// PASSWORD_REQUIRE_UPPERCASE .
+	PasswordRequireUppercase = "PASSWORD_REQUIRE_UPPERCASE"
+	// PASSWORD_REQUIRE_LOWERCASE .
+	PasswordRequireLowercase = "PASSWORD_REQUIRE_LOWERCASE"
+	// PASSWORD_REQUIRE_DIGIT .
+	PasswordRequireDigit = "PASSWORD_REQUIRE_DIGIT"
+	// PASSWORD_REQUIRE_SPECIAL .
+	PasswordRequireSpecial = "PASSWORD_REQUIRE_SPECIAL"
+	// PASSWORD_DISALLOW_PERSONAL_INFO .
+	PasswordDisallowPersonalInfo = "PASSWORD_DISALLOW_PERSONAL_INFO"
+	// PASSWORD_BREACHED_LIST .
+	PasswordBreachedList = "PASSWORD_BREACHED_LIST"
+	// PASSWORD_HISTORY_SIZE .
+	PasswordHistorySize = "PASSWORD_HISTORY_SIZE"
+	// PASSWORD_RESET_URL .
+	PasswordResetURL = "PASSWORD_RESET_URL"
+	// AVATAR_STORE .
+	AvatarStore = "AVATAR_STORE"
+	// AVATAR_STORE_DIR .
+	AvatarStoreDir = "AVATAR_STORE_DIR"
+	// AVATAR_BASE_URL .
+	AvatarBaseURL = "AVATAR_BASE_URL"
+	// AVATAR_MAX_SIZE .
+	AvatarMaxSize = "AVATAR_MAX_SIZE"
+	// USER_CACHE .
+	UserCache = "USER_CACHE"
+	// USER_CACHE_SIZE .
+	UserCacheSize = "USER_CACHE_SIZE"
+	// USER_CACHE_TTL .
+	UserCacheTTL = "USER_CACHE_TTL"
+	// PROBLEM_TYPE_BASE_URL .
+	ProblemTypeBaseURL = "PROBLEM_TYPE_BASE_URL"
 )
+
+// fileSuffix names the key of the file a secret is read from, e.g. a Docker or
+// Kubernetes secret.
+const fileSuffix = "_FILE"
+
+// Secrets are the keys also read from the file of their fileSuffix key, and
+// masked by Mask.
+var Secrets = []string{JwtPrivateKey, DatabaseUrl, DatabaseReplicaUrls}
+
+// Keys lists every key, in the order of the .env.example file.
+var Keys = []string{
+	EnvironmentEnv,
+	ApplicationName,
+	AppPort,
+	JwtPrivateKey,
+	JwtPrivateKeyFile,
+	DatabaseUrl,
+	DatabaseUrlFile,
+	DatabaseReplicaUrls,
+	DatabaseReplicaUrlsFile,
+	DbMaxOpenConns,
+	DbMaxIdleConns,
+	DbConnMaxLifetime,
+	DbConnectTimeout,
+	DbQueryTimeout,
+	TracesExporter,
+	MetricsExporter,
+	OtlpEndpoint,
+	LogLevel,
+	PhoneDefaultCountryCode,
+	PhoneCountryCodes,
+	Mailer,
+	MailerFileDir,
+	EmailVerificationURL,
+	PasswordHasher,
+	BcryptCost,
+	Argon2idMemory,
+	Argon2idIterations,
+	Argon2idParallelism,
+	PasswordMinLength,
+	PasswordMaxLength,
+	PasswordRequireUppercase,
+	PasswordRequireLowercase,
+	PasswordRequireDigit,
+	PasswordRequireSpecial,
+	PasswordDisallowPersonalInfo,
+	PasswordBreachedList,
+	PasswordHistorySize,
+	PasswordResetURL,
+	AvatarStore,
+	AvatarStoreDir,
+	AvatarBaseURL,
+	AvatarMaxSize,
+	UserCache,
+	UserCacheSize,
+	UserCacheTTL,
+	ProblemTypeBaseURL,
+}
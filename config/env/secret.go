@@ -0,0 +1,85 @@
+package env
+
+import (
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// masked replaces a secret, as url.URL.Redacted does a password.
+const masked = "xxxxx"
+
+func isSecret(key string) bool {
+	return slices.Contains(Secrets, key)
+}
+
+// secretFile is a secret read from a file, and the file it was read from.
+type secretFile struct {
+	modTime time.Time
+	size    int64
+	value   string
+}
+
+// readSecret reads the secret of key from path, again each time the file
+// changes, e.g. when the orchestrator rotates it. It keeps the last value read
+// while the file is missing, as it briefly is while being replaced.
+func (e *Env) readSecret(key, path string) (string, bool) {
+	e.mu.Lock()
+	cached, ok := e.files[path]
+	e.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if err == nil && ok && info.ModTime().Equal(cached.modTime) && info.Size() == cached.size {
+		return cached.value, true
+	}
+	var data []byte
+	if err == nil {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		e.fail(key, err)
+		return cached.value, ok
+	}
+	// Files usually end with a newline, which is not part of the secret.
+	value := strings.TrimRight(string(data), "\r\n")
+	e.mu.Lock()
+	e.files[path] = secretFile{modTime: info.ModTime(), size: info.Size(), value: value}
+	e.mu.Unlock()
+	return value, true
+}
+
+// Mask hides the value of a secret key: the passwords of the database URLs,
+// and the whole of the others. Other values are returned as they are.
+func Mask(key, value string) string {
+	if !isSecret(key) || value == "" {
+		return value
+	}
+	switch key {
+	case DatabaseUrl:
+		return maskURL(value)
+	case DatabaseReplicaUrls:
+		urls := strings.Split(value, ",")
+		for i, u := range urls {
+			urls[i] = maskURL(strings.TrimSpace(u))
+		}
+		return strings.Join(urls, ",")
+	default:
+		return masked
+	}
+}
+
+// maskURL masks the password of the user and of the query of rawURL, or the
+// whole of it when it does not parse.
+func maskURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" {
+		return masked
+	}
+	if q := u.Query(); q.Has("password") {
+		q.Set("password", masked)
+		u.RawQuery = q.Encode()
+	}
+	return u.Redacted()
+}
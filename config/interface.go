@@ -7,5 +7,45 @@ type IConfig interface {
 	ApplicationName() string
 	AppPort() int
 	DatabaseUrl() string
+	DatabaseReplicaUrls() string
+	DbMaxOpenConns() int
+	DbMaxIdleConns() int
+	DbConnMaxLifetime() string
+	DbConnectTimeout() string
+	DbQueryTimeout() string
 	JwtPrivateKey() string
+	TracesExporter() string
+	MetricsExporter() string
+	OtlpEndpoint() string
+	LogLevel() string
+	PhoneDefaultCountryCode() string
+	PhoneCountryCodes() string
+	Mailer() string
+	MailerFileDir() string
+	EmailVerificationURL() string
+	PasswordHasher() string
+	BcryptCost() int
+	Argon2idMemory() int
+	Argon2idIterations() int
+	Argon2idParallelism() int
+	PasswordMinLength() int
+	PasswordMaxLength() int
+	PasswordRequireUppercase() bool
+	PasswordRequireLowercase() bool
+	PasswordRequireDigit() bool
+	PasswordRequireSpecial() bool
+	PasswordDisallowPersonalInfo() bool
+	PasswordBreachedList() string
+	PasswordHistorySize() int
+	PasswordResetURL() string
+	AvatarStore() string
+	AvatarStoreDir() string
+	AvatarBaseURL() string
+	AvatarMaxSize() int
+	UserCache() string
+	UserCacheSize() int
+	UserCacheTTL() string
+	ProblemTypeBaseURL() string
+	// Validate reports every invalid value at once.
+	Validate() error
 }
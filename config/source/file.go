@@ -0,0 +1,102 @@
+package source
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// File reads a YAML (.yaml, .yml) or TOML (.toml) file. Keys are the names of
+// environment variables in any case, and nested tables are joined with
+// underscores, so that DB_MAX_OPEN_CONNS may be written as
+//
+//	db:
+//	  max_open_conns: 10
+//
+// Lists are joined with commas. Every key has to be one of known.
+func File(path string, known []string) (Source, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	var values map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("config file %s: want a .yaml, .yml or .toml file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	m := Map{}
+	if err := flatten(m, "", values); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	var unknown []string
+	for key := range m {
+		if !slices.Contains(known, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("config file %s: unknown keys %s", path, strings.Join(unknown, ", "))
+	}
+	return m, nil
+}
+
+func flatten(m Map, prefix string, values map[string]any) error {
+	for name, value := range values {
+		key := prefix + strings.ToUpper(name)
+		if table, ok := value.(map[string]any); ok {
+			if err := flatten(m, key+"_", table); err != nil {
+				return err
+			}
+			continue
+		}
+		s, err := scalar(key, value)
+		if err != nil {
+			return err
+		}
+		m[key] = s
+	}
+	return nil
+}
+
+func scalar(key string, value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := scalar(key, item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("%s: unsupported value %v", key, value)
+	}
+}
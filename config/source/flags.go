@@ -0,0 +1,45 @@
+package source
+
+import (
+	"flag"
+	"strings"
+)
+
+type flags struct {
+	fs *flag.FlagSet
+	// names maps the keys to their flags.
+	names map[string]string
+}
+
+// Flags defines a string flag on fs for each key, named after it in lower case
+// with dashes, e.g. --database-url for DATABASE_URL. The source sets the
+// flags given on the command line once fs is parsed.
+func Flags(fs *flag.FlagSet, keys []string) Source {
+	f := flags{fs: fs, names: map[string]string{}}
+	for _, key := range keys {
+		name := FlagName(key)
+		f.names[key] = name
+		fs.String(name, "", "sets "+key)
+	}
+	return f
+}
+
+// FlagName is the name of the flag of key.
+func FlagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+func (f flags) Lookup(key string) (string, bool) {
+	name, ok := f.names[key]
+	if !ok {
+		return "", false
+	}
+	var value string
+	var set bool
+	f.fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == name {
+			value, set = fl.Value.String(), true
+		}
+	})
+	return value, set
+}
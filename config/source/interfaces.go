@@ -0,0 +1,11 @@
+// This file contains the interface every configuration source implements.
+package source
+
+// Source looks configuration values up by the name of their environment
+// variable, e.g. DATABASE_URL.
+//
+//go:generate mockgen -source interfaces.go -destination interfaces.mock.gen.go -package=source
+type Source interface {
+	// Lookup reports whether the source sets key.
+	Lookup(key string) (string, bool)
+}
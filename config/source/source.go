@@ -0,0 +1,41 @@
+package source
+
+import "os"
+
+type env struct{}
+
+// Env returns the environment of the process. An empty variable is unset, as
+// in a .env file listing every key.
+func Env() Source {
+	return env{}
+}
+
+func (env) Lookup(key string) (string, bool) {
+	v := os.Getenv(key)
+	return v, v != ""
+}
+
+// Map is a Source of fixed values.
+type Map map[string]string
+
+func (m Map) Lookup(key string) (string, bool) {
+	v, ok := m[key]
+	return v, ok
+}
+
+type layers []Source
+
+// Layers returns the value of the first source that sets a key, so earlier
+// sources take precedence over later ones.
+func Layers(sources ...Source) Source {
+	return layers(sources)
+}
+
+func (l layers) Lookup(key string) (string, bool) {
+	for _, s := range l {
+		if v, ok := s.Lookup(key); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
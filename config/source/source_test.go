@@ -0,0 +1,123 @@
+package source
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var known = []string{"APP_PORT", "DATABASE_URL", "DB_MAX_OPEN_CONNS", "PHONE_COUNTRY_CODES", "PASSWORD_REQUIRE_DIGIT", "ARGON2ID_MEMORY"}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func lookupAll(s Source, keys ...string) map[string]string {
+	values := map[string]string{}
+	for _, key := range keys {
+		if v, ok := s.Lookup(key); ok {
+			values[key] = v
+		}
+	}
+	return values
+}
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+	want := map[string]string{
+		"APP_PORT":               "8080",
+		"DATABASE_URL":           "postgres://db/users",
+		"DB_MAX_OPEN_CONNS":      "10",
+		"PHONE_COUNTRY_CODES":    "62:10-13,65",
+		"PASSWORD_REQUIRE_DIGIT": "false",
+		"ARGON2ID_MEMORY":        "19456",
+	}
+
+	t.Run("yaml", func(t *testing.T) {
+		s, err := File(writeFile(t, "config.yaml", `
+app_port: 8080
+DATABASE_URL: postgres://db/users
+db:
+  max_open_conns: 10
+phone_country_codes: ["62:10-13", "65"]
+password_require_digit: false
+argon2id:
+  memory: 19456
+`), known)
+		require.NoError(t, err)
+		assert.Equal(t, want, lookupAll(s, known...))
+	})
+
+	t.Run("toml", func(t *testing.T) {
+		s, err := File(writeFile(t, "config.toml", `
+app_port = 8080
+database_url = "postgres://db/users"
+phone_country_codes = ["62:10-13", "65"]
+password_require_digit = false
+
+[db]
+max_open_conns = 10
+
+[argon2id]
+memory = 19456
+`), known)
+		require.NoError(t, err)
+		assert.Equal(t, want, lookupAll(s, known...))
+	})
+
+	t.Run("unknown keys", func(t *testing.T) {
+		path := writeFile(t, "config.yml", "app_port: 8080\ndatabse_url: x\nargon2id:\n  memroy: 1\n")
+		_, err := File(path, known)
+		assert.EqualError(t, err, "config file "+path+": unknown keys ARGON2ID_MEMROY, DATABSE_URL")
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := File(writeFile(t, "config.json", "{}"), known)
+		assert.ErrorContains(t, err, "want a .yaml, .yml or .toml file")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := File(filepath.Join(t.TempDir(), "config.yaml"), known)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestFlags(t *testing.T) {
+	t.Parallel()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	s := Flags(fs, known)
+	require.NoError(t, fs.Parse([]string{"--app-port=8080", "--database-url", ""}))
+
+	assert.Equal(t, map[string]string{"APP_PORT": "8080", "DATABASE_URL": ""}, lookupAll(s, known...))
+	_, ok := s.Lookup("UNKNOWN")
+	assert.False(t, ok)
+}
+
+func TestLayers(t *testing.T) {
+	t.Parallel()
+
+	s := Layers(
+		Map{"APP_PORT": "1"},
+		Map{"APP_PORT": "2", "DATABASE_URL": "postgres://db"},
+		Map{"DATABASE_URL": "postgres://other", "DB_MAX_OPEN_CONNS": "3"},
+	)
+	assert.Equal(t, map[string]string{
+		"APP_PORT":          "1",
+		"DATABASE_URL":      "postgres://db",
+		"DB_MAX_OPEN_CONNS": "3",
+	}, lookupAll(s, known...))
+}
+
+func TestEnv(t *testing.T) {
+	t.Setenv("APP_PORT", "8080")
+	t.Setenv("DATABASE_URL", "")
+
+	assert.Equal(t, map[string]string{"APP_PORT": "8080"}, lookupAll(Env(), known...))
+}
@@ -1,16 +1,25 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"strconv"
 
 	"github.com/SawitProRecruitment/UserService/handler/httpcontext"
 	"github.com/SawitProRecruitment/UserService/handler/middleware"
 	_ "github.com/SawitProRecruitment/UserService/lib/errors"
+	"github.com/SawitProRecruitment/UserService/lib/patch"
 	"github.com/SawitProRecruitment/UserService/service"
 	"github.com/labstack/echo/v4"
 )
 
+// multipartOverhead is what an avatar upload may add to the picture: the
+// boundaries and headers of its parts.
+const multipartOverhead = 64 << 10
+
 func bindAndValidate(c echo.Context, r any) error {
 	if err := c.Bind(r); err != nil {
 		return err
@@ -21,18 +30,33 @@ func bindAndValidate(c echo.Context, r any) error {
 	return nil
 }
 
+// bindMergePatch decodes a JSON Merge Patch body, sent either as
+// application/merge-patch+json or application/json, which echo.Bind cannot do.
+func bindMergePatch(c echo.Context, r any) error {
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if mediaType != patch.MIMEApplicationMergePatchJSON && mediaType != echo.MIMEApplicationJSON {
+		return echo.ErrUnsupportedMediaType
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(r); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return c.Validate(r)
+}
+
 // @Summary Get user
-// @Description Get user data
+// @Description Get user data, the ETag is the version of the user to send back in If-Match
 // @Router /v1/user [get]
 // @Produce json
 // @Param Authorization header string true "Bearer"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} responseWithData
+// @Success 304
 // @Failure 403 {object} errors.ErrorResponse
 // @Failure 404 {object} errors.ErrorResponse
 // @Failure 500 {object} errors.ErrorResponse
 // @Security ApiKeyAuth
 func (s *Server) GetCurrentUser(c echo.Context) error {
-	err := middleware.Auth(c)
+	err := middleware.Auth(c, s.TokenService)
 	if err != nil {
 		return err
 	}
@@ -47,6 +71,10 @@ func (s *Server) GetCurrentUser(c echo.Context) error {
 		return err
 	}
 
+	c.Response().Header().Set(headerETag, userETag(u.Version))
+	if ifNoneMatch(c.Request().Header.Values(headerIfNoneMatch), u.Version) {
+		return c.NoContent(http.StatusNotModified)
+	}
 	return c.JSON(http.StatusOK, newSuccessGetByIDResponse(u))
 }
 
@@ -56,6 +84,7 @@ func (s *Server) GetCurrentUser(c echo.Context) error {
 // @Produce json
 // @Param name body string true "Name"
 // @Param phone body string true "Phone Number"
+// @Param email body string false "Email"
 // @Param password body string true "Password"
 // @Success 200 {object} responseWithData
 // @Failure 409 {object} errors.ErrorResponse
@@ -74,18 +103,26 @@ func (s *Server) Register(c echo.Context) error {
 }
 
 // @Summary Update user
-// @Description Update user data
+// @Description Update user data with a JSON Merge Patch, absent fields are left unchanged and null removes optional ones
 // @Router /v1/user [patch]
+// @Accept application/merge-patch+json,json
 // @Produce json
 // @Param Authorization header string true "Bearer"
-// @Param name body string true "Name"
-// @Param phone body string true "Phone Number"
+// @Param If-Match header string false "ETag from GET /v1/user, the update fails with 412 if the user changed since"
+// @Param name body string false "Name"
+// @Param phone body string false "Phone Number"
+// @Param email body string false "Email"
+// @Param date_of_birth body string false "Date of birth, YYYY-MM-DD"
+// @Param avatar_url body string false "Avatar URL"
+// @Param locale body string false "BCP 47 language tag"
+// @Param timezone body string false "IANA time zone"
 // @Success 200 {object} baseResponse
 // @Failure 403 {object} errors.ErrorResponse
 // @Failure 409 {object} errors.ErrorResponse
+// @Failure 412 {object} errors.ErrorResponse
 // @Failure 500 {object} errors.ErrorResponse
 func (s *Server) UpdateProfile(c echo.Context) error {
-	err := middleware.Auth(c)
+	err := middleware.Auth(c, s.TokenService)
 	if err != nil {
 		return err
 	}
@@ -96,14 +133,19 @@ func (s *Server) UpdateProfile(c echo.Context) error {
 		return fmt.Errorf("cannot get user from context")
 	}
 	var payload service.PayloadUpdate
+	if err := bindMergePatch(c, &payload); err != nil {
+		return err
+	}
 	payload.Id = userJwt.ID
-	if err := bindAndValidate(c, &payload); err != nil {
+	payload.Versions, err = ifMatchVersions(c.Request().Header.Values(headerIfMatch))
+	if err != nil {
 		return err
 	}
-	err = s.Service.UpdateProfile(ctx, payload)
+	version, err := s.Service.UpdateProfile(ctx, payload)
 	if err != nil {
 		return err
 	}
+	c.Response().Header().Set(headerETag, userETag(version))
 	return c.JSON(http.StatusOK, newBaseResponse("Successfully update user!"))
 }
 
@@ -111,7 +153,8 @@ func (s *Server) UpdateProfile(c echo.Context) error {
 // @Description Login user
 // @Router /v1/users/login [post]
 // @Produce json
-// @Param phone body string true "Phone Number"
+// @Param phone body string false "Phone Number, required without email"
+// @Param email body string false "Verified email, required without phone"
 // @Param password body string true "Password"
 // @Success 200 {object} responseWithData
 // @Failure 400 {object} errors.ErrorResponse
@@ -128,3 +171,199 @@ func (s *Server) Login(c echo.Context) error {
 	}
 	return c.JSON(http.StatusOK, newSuccessLogin(res))
 }
+
+// @Summary Verify email
+// @Description Verify the email of a user with the token sent by email
+// @Router /v1/users/email/verify [post]
+// @Produce json
+// @Param token body string true "Verification token"
+// @Success 200 {object} baseResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+func (s *Server) VerifyEmail(c echo.Context) error {
+	ctx := c.Request().Context()
+	var payload service.PayloadVerifyEmail
+	if err := bindAndValidate(c, &payload); err != nil {
+		return err
+	}
+	if err := s.Service.VerifyEmail(ctx, payload); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, newBaseResponse("Successfully verify email!"))
+}
+
+// @Summary Resend email verification
+// @Description Send a new verification link to the unverified email of the user
+// @Router /v1/user/email/verification [post]
+// @Produce json
+// @Param Authorization header string true "Bearer"
+// @Success 200 {object} baseResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+func (s *Server) ResendEmailVerification(c echo.Context) error {
+	err := middleware.Auth(c, s.TokenService)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	userJwt, ok := httpcontext.GetUserJWT(c)
+	if !ok {
+		return fmt.Errorf("cannot get user from context")
+	}
+	if err := s.Service.ResendEmailVerification(ctx, userJwt.ID); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, newBaseResponse("Successfully send email verification!"))
+}
+
+// @Summary Change password
+// @Description Change the password of the current user
+// @Router /v1/user/password [put]
+// @Produce json
+// @Param Authorization header string true "Bearer"
+// @Param current_password body string true "Current password"
+// @Param new_password body string true "New password"
+// @Success 200 {object} baseResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+func (s *Server) ChangePassword(c echo.Context) error {
+	err := middleware.Auth(c, s.TokenService)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	userJwt, ok := httpcontext.GetUserJWT(c)
+	if !ok {
+		return fmt.Errorf("cannot get user from context")
+	}
+	var payload service.PayloadChangePassword
+	if err := bindAndValidate(c, &payload); err != nil {
+		return err
+	}
+	payload.Id = userJwt.ID
+	if err := s.Service.ChangePassword(ctx, payload); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, newBaseResponse("Successfully change password!"))
+}
+
+// @Summary Request password reset
+// @Description Send a password reset link to a verified email
+// @Router /v1/users/password/reset-request [post]
+// @Produce json
+// @Param email body string true "Email"
+// @Success 200 {object} baseResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+func (s *Server) RequestPasswordReset(c echo.Context) error {
+	ctx := c.Request().Context()
+	var payload service.PayloadRequestPasswordReset
+	if err := bindAndValidate(c, &payload); err != nil {
+		return err
+	}
+	if err := s.Service.RequestPasswordReset(ctx, payload); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, newBaseResponse("If the email is registered and verified, a password reset link has been sent."))
+}
+
+// @Summary Reset password
+// @Description Set a new password with the token sent by email
+// @Router /v1/users/password/reset [post]
+// @Produce json
+// @Param token body string true "Password reset token"
+// @Param password body string true "New password"
+// @Success 200 {object} baseResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+func (s *Server) ResetPassword(c echo.Context) error {
+	ctx := c.Request().Context()
+	var payload service.PayloadResetPassword
+	if err := bindAndValidate(c, &payload); err != nil {
+		return err
+	}
+	if err := s.Service.ResetPassword(ctx, payload); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, newBaseResponse("Successfully reset password!"))
+}
+
+// @Summary Upload avatar
+// @Description Replace the avatar with an uploaded JPEG, PNG, GIF or WebP picture, stored as square JPEG thumbnails without metadata
+// @Router /v1/user/avatar [put]
+// @Accept multipart/form-data
+// @Produce json
+// @Param Authorization header string true "Bearer"
+// @Param avatar formData file true "Picture"
+// @Success 200 {object} responseWithData
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 413 {object} errors.ErrorResponse
+// @Failure 415 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+func (s *Server) UploadAvatar(c echo.Context) error {
+	err := middleware.Auth(c, s.TokenService)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	userJwt, ok := httpcontext.GetUserJWT(c)
+	if !ok {
+		return fmt.Errorf("cannot get user from context")
+	}
+	// FormFile reads the whole body, which is not to be left unbounded.
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, s.AvatarMaxSize+multipartOverhead)
+	header, err := c.FormFile("avatar")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return service.AvatarTooLarge(s.AvatarMaxSize)
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "missing avatar file").SetInternal(err)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	result, err := s.Service.UploadAvatar(ctx, service.PayloadUploadAvatar{
+		Id:   userJwt.ID,
+		File: file,
+	})
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set(headerETag, userETag(result.Version))
+	return c.JSON(http.StatusOK, newSuccessUploadAvatarResponse(result))
+}
+
+// @Summary Get avatar
+// @Description Download an avatar thumbnail, its URL changes with every upload so it can be cached forever
+// @Router /v1/avatars/{userId}/{file} [get]
+// @Produce jpeg
+// @Param userId path int true "User id"
+// @Param file path string true "Thumbnail file name"
+// @Success 200 {file} binary
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+func (s *Server) GetAvatar(c echo.Context, userId int64, file string) error {
+	ctx := c.Request().Context()
+	object, err := s.Service.GetAvatar(ctx, userId, file)
+	if err != nil {
+		return err
+	}
+	defer object.Body.Close()
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderCacheControl, "public, max-age=31536000, immutable")
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(object.Size, 10))
+	return c.Stream(http.StatusOK, object.ContentType, object.Body)
+}
@@ -5,17 +5,25 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/SawitProRecruitment/UserService/lib/blob"
 	"github.com/SawitProRecruitment/UserService/lib/errors"
 	"github.com/SawitProRecruitment/UserService/lib/jwt"
+	"github.com/SawitProRecruitment/UserService/lib/patch"
 	"github.com/SawitProRecruitment/UserService/lib/validator"
+	"github.com/SawitProRecruitment/UserService/repository"
 	"github.com/SawitProRecruitment/UserService/service"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	playgroundvalidator "gopkg.in/go-playground/validator.v9"
 )
 
 type component struct {
@@ -29,7 +37,11 @@ type component struct {
 func setupService(t *testing.T) *component {
 	g := gomock.NewController(t)
 	service := service.NewMockServiceInterface(g)
-	token, _ := jwt.GenerateToken(jwt.User{
+	tokens := jwt.NewTokenService(jwt.Options{
+		PrivateKey: func() string { return "test-key" },
+		Issuer:     "test",
+	})
+	token, _ := tokens.GenerateToken(jwt.User{
 		ID:    1,
 		Name:  "rotan",
 		Phone: "+62123456789",
@@ -38,7 +50,8 @@ func setupService(t *testing.T) *component {
 	return &component{
 		ctx: context.Background(),
 		handler: NewServer(NewServerOptions{
-			Service: service,
+			Service:      service,
+			TokenService: tokens,
 		}),
 		service:   service,
 		mockedErr: fmt.Errorf("mocked error"),
@@ -79,11 +92,28 @@ func TestServer_GetCurrentUser(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := echo.New().NewContext(req, rec)
 
-		s.service.EXPECT().GetByID(gomock.Any(), int64(1)).Return(&service.User{Id: 1, Name: "rotan", Phone: "+62123456789"}, nil)
+		s.service.EXPECT().GetByID(gomock.Any(), int64(1)).Return(&service.User{Id: 1, Name: "rotan", Phone: "+62123456789", Version: 3}, nil)
 
 		err := s.handler.GetCurrentUser(c)
 		assert.Nil(t, err)
 		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		s := setupService(t)
+		req := httptest.NewRequest(http.MethodGet, "/url", nil)
+		req.Header.Set("Authorization", "Bearer "+s.jwt)
+		req.Header.Set("If-None-Match", `"2", W/"3"`)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		s.service.EXPECT().GetByID(gomock.Any(), int64(1)).Return(&service.User{Id: 1, Version: 3}, nil)
+
+		err := s.handler.GetCurrentUser(c)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
 	})
 }
 func TestServer_Register(t *testing.T) {
@@ -125,8 +155,40 @@ func TestServer_Register(t *testing.T) {
 		c := echo.New().NewContext(req, rec)
 		c.Echo().Validator = validator.NewValidator()
 
+		s.service.EXPECT().InsertUser(gomock.Any(), payload).Return(nil, service.ErrPasswordSpecial)
+
 		err := s.handler.Register(c)
-		assert.NotNil(t, err)
+		assert.ErrorIs(t, err, service.ErrPasswordSpecial)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		for locale, message := range map[string]string{
+			"en": "conflict with the current state of the user",
+			"id": "bertentangan dengan kondisi pengguna saat ini",
+		} {
+			s := setupService(t)
+			payload := service.PayloadInsert{
+				Name:     "rotan",
+				Phone:    "+628123456789",
+				Password: "Password123!",
+			}
+			bs, _ := json.Marshal(payload)
+			req := httptest.NewRequest(http.MethodPost, "/url", bytes.NewBuffer(bs))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("Accept-Language", locale)
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			c.Echo().Validator = validator.NewValidator()
+
+			s.service.EXPECT().InsertUser(gomock.Any(), payload).Return(nil, service.ErrConflict)
+
+			errors.CustomHTTPErrorHandler(s.handler.Register(c), c)
+			var res errors.ErrorResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			assert.Equal(t, http.StatusConflict, rec.Code)
+			assert.Equal(t, service.CodeUserConflict, res.ErrorCode)
+			assert.Equal(t, message, res.Message, locale)
+		}
 	})
 
 	t.Run("invalid name", func(t *testing.T) {
@@ -169,40 +231,118 @@ func TestServer_Register(t *testing.T) {
 func TestServer_UpdateProfile(t *testing.T) {
 	t.Parallel()
 
-	t.Run("success update user", func(t *testing.T) {
-		s := setupService(t)
-		payload := service.PayloadUpdate{
-			Name:  "rotan",
-			Phone: "+628123456789",
-		}
-		bs, _ := json.Marshal(payload)
-		req := httptest.NewRequest(http.MethodPost, "/url", bytes.NewBuffer(bs))
-		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	newContext := func(s *component, contentType, body string, ifMatch ...string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodPatch, "/url", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, contentType)
 		req.Header.Set("Authorization", "Bearer "+s.jwt)
+		for _, tag := range ifMatch {
+			req.Header.Add("If-Match", tag)
+		}
 		rec := httptest.NewRecorder()
 		c := echo.New().NewContext(req, rec)
 		c.Echo().Validator = validator.NewValidator()
+		return c, rec
+	}
 
-		s.service.EXPECT().UpdateProfile(gomock.Any(), payload).Return(nil)
+	t.Run("success update user", func(t *testing.T) {
+		s := setupService(t)
+		c, rec := newContext(s, echo.MIMEApplicationJSON, `{"name": "rotan", "phone": "+628123456789"}`)
+
+		s.service.EXPECT().UpdateProfile(gomock.Any(), service.PayloadUpdate{
+			Id:    1,
+			Name:  patch.Value("rotan"),
+			Phone: patch.Value("+628123456789"),
+		}).Return(int64(2), nil)
 
 		err := s.handler.UpdateProfile(c)
 		assert.Nil(t, err)
 		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
 	})
 
-	t.Run("invalid name", func(t *testing.T) {
+	t.Run("an empty patch keeps the etag", func(t *testing.T) {
 		s := setupService(t)
-		payload := service.PayloadUpdate{
-			Name:  "r",
-			Phone: "+628123456789",
+		repo := repository.NewMemoryRepository(repository.NewMemoryRepositoryOptions{})
+		id, err := repo.InsertUser(s.ctx, repository.User{Name: "rotan", Phone: "+628123456789", Password: "hash"})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), *id, "the id of the token")
+		s.handler.Service = service.NewService(service.NewServiceOption{UserRepository: repo})
+		user, _ := repo.GetUserById(s.ctx, *id)
+		etag := userETag(user.Version)
+
+		for _, body := range []string{`{}`, `{"name": "rotan"}`} {
+			c, rec := newContext(s, patch.MIMEApplicationMergePatchJSON, body, etag)
+			err := s.handler.UpdateProfile(c)
+			assert.Nil(t, err, body)
+			assert.Equal(t, http.StatusOK, rec.Code, body)
+			assert.Equal(t, etag, rec.Header().Get("ETag"), body)
 		}
-		bs, _ := json.Marshal(payload)
-		req := httptest.NewRequest(http.MethodPost, "/url", bytes.NewBuffer(bs))
-		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-		req.Header.Set("Authorization", "Bearer "+s.jwt)
-		rec := httptest.NewRecorder()
-		c := echo.New().NewContext(req, rec)
-		c.Echo().Validator = validator.NewValidator()
+	})
+
+	t.Run("success merge patch", func(t *testing.T) {
+		s := setupService(t)
+		c, rec := newContext(s, patch.MIMEApplicationMergePatchJSON, `{"id": 2, "timezone": "Asia/Jakarta", "avatar_url": null}`)
+
+		s.service.EXPECT().UpdateProfile(gomock.Any(), service.PayloadUpdate{
+			Id:        1,
+			Timezone:  patch.Value("Asia/Jakarta"),
+			AvatarURL: patch.Null[string](),
+		}).Return(int64(2), nil)
+
+		err := s.handler.UpdateProfile(c)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("if-match versions", func(t *testing.T) {
+		s := setupService(t)
+		c, rec := newContext(s, echo.MIMEApplicationJSON, `{"name": "rotan"}`, `"1", W/"2"`, `"abc"`)
+
+		s.service.EXPECT().UpdateProfile(gomock.Any(), service.PayloadUpdate{
+			Id:       1,
+			Versions: []int64{1},
+			Name:     patch.Value("rotan"),
+		}).Return(int64(2), nil)
+
+		err := s.handler.UpdateProfile(c)
+		assert.Nil(t, err)
+		assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	})
+
+	t.Run("if-match any", func(t *testing.T) {
+		s := setupService(t)
+		c, _ := newContext(s, echo.MIMEApplicationJSON, `{"name": "rotan"}`, `*`)
+
+		s.service.EXPECT().UpdateProfile(gomock.Any(), service.PayloadUpdate{
+			Id:   1,
+			Name: patch.Value("rotan"),
+		}).Return(int64(2), nil)
+
+		err := s.handler.UpdateProfile(c)
+		assert.Nil(t, err)
+	})
+
+	t.Run("if-match without strong version", func(t *testing.T) {
+		s := setupService(t)
+		c, _ := newContext(s, echo.MIMEApplicationJSON, `{"name": "rotan"}`, `W/"1"`)
+
+		err := s.handler.UpdateProfile(c)
+		assert.ErrorIs(t, err, service.ErrVersionMismatch)
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		s := setupService(t)
+		c, _ := newContext(s, echo.MIMEApplicationJSON, `{"name": "rotan"}`, `"1"`)
+
+		s.service.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(int64(0), service.ErrVersionMismatch)
+
+		err := s.handler.UpdateProfile(c)
+		assert.ErrorIs(t, err, service.ErrVersionMismatch)
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		s := setupService(t)
+		c, _ := newContext(s, echo.MIMEApplicationJSON, `{"name": "r", "phone": "+628123456789"}`)
 
 		err := s.handler.UpdateProfile(c)
 		assert.NotNil(t, err)
@@ -210,22 +350,40 @@ func TestServer_UpdateProfile(t *testing.T) {
 
 	t.Run("invalid phone", func(t *testing.T) {
 		s := setupService(t)
-		payload := service.PayloadUpdate{
-			Name:  "rotan",
-			Phone: "+28123456789",
-		}
-		bs, _ := json.Marshal(payload)
-		req := httptest.NewRequest(http.MethodPost, "/url", bytes.NewBuffer(bs))
-		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-		req.Header.Set("Authorization", "Bearer "+s.jwt)
-		rec := httptest.NewRecorder()
-		c := echo.New().NewContext(req, rec)
-		c.Echo().Validator = validator.NewValidator()
+		c, _ := newContext(s, echo.MIMEApplicationJSON, `{"name": "rotan", "phone": "+28123456789"}`)
 
 		err := s.handler.UpdateProfile(c)
 		assert.NotNil(t, err)
 	})
 
+	t.Run("empty strings are validated", func(t *testing.T) {
+		for _, body := range []string{`{"name": ""}`, `{"phone": ""}`, `{"date_of_birth": ""}`, `{"locale": ""}`} {
+			s := setupService(t)
+			c, _ := newContext(s, echo.MIMEApplicationJSON, body)
+
+			err := s.handler.UpdateProfile(c)
+			var validErrs playgroundvalidator.ValidationErrors
+			assert.ErrorAs(t, err, &validErrs, body)
+		}
+	})
+
+	t.Run("invalid optional fields", func(t *testing.T) {
+		s := setupService(t)
+		c, _ := newContext(s, echo.MIMEApplicationJSON, `{"date_of_birth": "2999-01-01", "locale": "??", "timezone": "Mars/Olympus"}`)
+
+		err := s.handler.UpdateProfile(c)
+		var validErrs playgroundvalidator.ValidationErrors
+		assert.ErrorAs(t, err, &validErrs)
+		assert.Len(t, validErrs, 3)
+	})
+
+	t.Run("unsupported media type", func(t *testing.T) {
+		s := setupService(t)
+		c, _ := newContext(s, echo.MIMEApplicationForm, `name=rotan`)
+
+		err := s.handler.UpdateProfile(c)
+		assert.ErrorIs(t, err, echo.ErrUnsupportedMediaType)
+	})
 }
 
 func TestServer_Login(t *testing.T) {
@@ -255,11 +413,11 @@ func TestServer_Login(t *testing.T) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 	})
 
-	t.Run("invalid password", func(t *testing.T) {
+	t.Run("password policy is not checked on login", func(t *testing.T) {
 		s := setupService(t)
 		payload := service.PayloadLogin{
 			Phone:    "+628123456789",
-			Password: "Password123",
+			Password: "password",
 		}
 		bs, _ := json.Marshal(payload)
 		req := httptest.NewRequest(http.MethodPost, "/url", bytes.NewBuffer(bs))
@@ -268,8 +426,10 @@ func TestServer_Login(t *testing.T) {
 		c := echo.New().NewContext(req, rec)
 		c.Echo().Validator = validator.NewValidator()
 
+		s.service.EXPECT().Login(gomock.Any(), payload).Return(nil, service.ErrInvalidCredentials)
+
 		err := s.handler.Login(c)
-		assert.NotNil(t, err)
+		assert.ErrorIs(t, err, service.ErrInvalidCredentials)
 	})
 
 	t.Run("invalid phone", func(t *testing.T) {
@@ -288,4 +448,298 @@ func TestServer_Login(t *testing.T) {
 		err := s.handler.Login(c)
 		assert.NotNil(t, err)
 	})
+
+	t.Run("success login with email", func(t *testing.T) {
+		s := setupService(t)
+		payload := service.PayloadLogin{
+			Email:    "rotan@example.com",
+			Password: "Password123!",
+		}
+		bs, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/url", bytes.NewBuffer(bs))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Echo().Validator = validator.NewValidator()
+
+		s.service.EXPECT().Login(gomock.Any(), payload).Return(&service.ResponseLogin{
+			UserId: 1,
+			Token:  s.jwt,
+		}, nil)
+
+		err := s.handler.Login(c)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("missing phone and email", func(t *testing.T) {
+		s := setupService(t)
+		payload := service.PayloadLogin{
+			Password: "Password123!",
+		}
+		bs, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/url", bytes.NewBuffer(bs))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Echo().Validator = validator.NewValidator()
+
+		err := s.handler.Login(c)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestServer_VerifyEmail(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success verify email", func(t *testing.T) {
+		s := setupService(t)
+		payload := service.PayloadVerifyEmail{Token: "token"}
+		bs, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/url", bytes.NewBuffer(bs))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Echo().Validator = validator.NewValidator()
+
+		s.service.EXPECT().VerifyEmail(gomock.Any(), payload).Return(nil)
+
+		err := s.handler.VerifyEmail(c)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		s := setupService(t)
+		payload := service.PayloadVerifyEmail{Token: "token"}
+		bs, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/url", bytes.NewBuffer(bs))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Echo().Validator = validator.NewValidator()
+
+		s.service.EXPECT().VerifyEmail(gomock.Any(), payload).Return(service.ErrInvalidEmailToken)
+
+		err := s.handler.VerifyEmail(c)
+		assert.ErrorIs(t, err, service.ErrInvalidEmailToken)
+	})
+}
+
+func TestServer_ResendEmailVerification(t *testing.T) {
+	t.Parallel()
+
+	t.Run("error because no auth", func(t *testing.T) {
+		s := setupService(t)
+		req := httptest.NewRequest(http.MethodPost, "/url", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		err := s.handler.ResendEmailVerification(c)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("success resend email verification", func(t *testing.T) {
+		s := setupService(t)
+		req := httptest.NewRequest(http.MethodPost, "/url", nil)
+		req.Header.Set("Authorization", "Bearer "+s.jwt)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		s.service.EXPECT().ResendEmailVerification(gomock.Any(), int64(1)).Return(nil)
+
+		err := s.handler.ResendEmailVerification(c)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestServer_ChangePassword(t *testing.T) {
+	t.Parallel()
+
+	t.Run("error because no auth", func(t *testing.T) {
+		s := setupService(t)
+		req := httptest.NewRequest(http.MethodPut, "/url", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		err := s.handler.ChangePassword(c)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("success change password", func(t *testing.T) {
+		s := setupService(t)
+		payload := service.PayloadChangePassword{
+			CurrentPassword: "Password123!",
+			NewPassword:     "Kebun-Sawit-99",
+		}
+		bs, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPut, "/url", bytes.NewBuffer(bs))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("Authorization", "Bearer "+s.jwt)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Echo().Validator = validator.NewValidator()
+
+		payload.Id = 1
+		s.service.EXPECT().ChangePassword(gomock.Any(), payload).Return(nil)
+
+		err := s.handler.ChangePassword(c)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestServer_ResetPassword(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success request password reset", func(t *testing.T) {
+		s := setupService(t)
+		payload := service.PayloadRequestPasswordReset{Email: "rotan@example.com"}
+		bs, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/url", bytes.NewBuffer(bs))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Echo().Validator = validator.NewValidator()
+
+		s.service.EXPECT().RequestPasswordReset(gomock.Any(), payload).Return(nil)
+
+		err := s.handler.RequestPasswordReset(c)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("success reset password", func(t *testing.T) {
+		s := setupService(t)
+		payload := service.PayloadResetPassword{Token: "token", Password: "Kebun-Sawit-99"}
+		bs, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/url", bytes.NewBuffer(bs))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Echo().Validator = validator.NewValidator()
+
+		s.service.EXPECT().ResetPassword(gomock.Any(), payload).Return(nil)
+
+		err := s.handler.ResetPassword(c)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestServer_UploadAvatar(t *testing.T) {
+	t.Parallel()
+
+	newContext := func(s *component, field string) (echo.Context, *httptest.ResponseRecorder) {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile(field, "avatar.png")
+		part.Write([]byte("picture"))
+		form.Close()
+		req := httptest.NewRequest(http.MethodPut, "/url", &body)
+		req.Header.Set(echo.HeaderContentType, form.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+s.jwt)
+		rec := httptest.NewRecorder()
+		return echo.New().NewContext(req, rec), rec
+	}
+
+	t.Run("success upload avatar", func(t *testing.T) {
+		s := setupService(t)
+		c, rec := newContext(s, "avatar")
+
+		s.service.EXPECT().UploadAvatar(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, payload service.PayloadUploadAvatar) (*service.ResponseUploadAvatar, error) {
+			assert.Equal(t, int64(1), payload.Id)
+			data, _ := io.ReadAll(payload.File)
+			assert.Equal(t, "picture", string(data))
+			return &service.ResponseUploadAvatar{
+				Version: 3,
+				Avatars: map[string]string{"small": "http://localhost/v1/avatars/1/abc-small.jpg"},
+			}, nil
+		})
+
+		err := s.handler.UploadAvatar(c)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+		assert.Contains(t, rec.Body.String(), `"small":"http://localhost/v1/avatars/1/abc-small.jpg"`)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		s := setupService(t)
+		c, _ := newContext(s, "picture")
+
+		err := s.handler.UploadAvatar(c)
+		var he *echo.HTTPError
+		assert.ErrorAs(t, err, &he)
+		assert.Equal(t, http.StatusBadRequest, he.Code)
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		s := setupService(t)
+		s.handler.AvatarMaxSize = 1 << 10
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("avatar", "avatar.png")
+		part.Write(bytes.Repeat([]byte("a"), 1<<10+multipartOverhead))
+		form.Close()
+		req := httptest.NewRequest(http.MethodPut, "/url", &body)
+		req.Header.Set(echo.HeaderContentType, form.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+s.jwt)
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		err := s.handler.UploadAvatar(c)
+		var e *errors.Error
+		assert.ErrorAs(t, err, &e)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, e.Status)
+		assert.Equal(t, service.CodeAvatarTooLarge, e.Code)
+		assert.Equal(t, "avatar must be at most 1 KiB", e.Message)
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		s := setupService(t)
+		c, _ := newContext(s, "avatar")
+
+		s.service.EXPECT().UploadAvatar(gomock.Any(), gomock.Any()).Return(nil, service.ErrAvatarType)
+
+		err := s.handler.UploadAvatar(c)
+		assert.ErrorIs(t, err, service.ErrAvatarType)
+	})
+}
+
+func TestServer_GetAvatar(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success get avatar", func(t *testing.T) {
+		s := setupService(t)
+		req := httptest.NewRequest(http.MethodGet, "/url", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		s.service.EXPECT().GetAvatar(gomock.Any(), int64(1), "abc-small.jpg").Return(&blob.Object{
+			Body:        io.NopCloser(strings.NewReader("jpeg")),
+			ContentType: "image/jpeg",
+			Size:        4,
+		}, nil)
+
+		err := s.handler.GetAvatar(c, 1, "abc-small.jpg")
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/jpeg", rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "4", rec.Header().Get(echo.HeaderContentLength))
+		assert.Contains(t, rec.Header().Get(echo.HeaderCacheControl), "immutable")
+		assert.Equal(t, "jpeg", rec.Body.String())
+	})
+
+	t.Run("avatar not found", func(t *testing.T) {
+		s := setupService(t)
+		req := httptest.NewRequest(http.MethodGet, "/url", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		s.service.EXPECT().GetAvatar(gomock.Any(), int64(1), "abc-small.jpg").Return(nil, service.ErrAvatarNotFound)
+
+		err := s.handler.GetAvatar(c, 1, "abc-small.jpg")
+		assert.ErrorIs(t, err, service.ErrAvatarNotFound)
+	})
 }
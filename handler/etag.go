@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/SawitProRecruitment/UserService/service"
+)
+
+const (
+	headerETag        = "ETag"
+	headerIfMatch     = "If-Match"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+// userETag is the strong entity tag of a user, its quoted version.
+func userETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseETags splits the values of an If-Match or If-None-Match header into
+// their entity tags. wildcard is true for "*".
+func parseETags(values []string) (tags []string, wildcard bool) {
+	for _, tag := range strings.Split(strings.Join(values, ","), ",") {
+		tag = strings.TrimSpace(tag)
+		switch tag {
+		case "":
+		case "*":
+			wildcard = true
+		default:
+			tags = append(tags, tag)
+		}
+	}
+	return tags, wildcard
+}
+
+// ifMatchVersions returns the versions listed in an If-Match header, nil when
+// the update is unconditional. If-Match uses the strong comparison, so weak
+// tags and tags that are not a version never match and, when nothing else is
+// listed, the precondition fails straight away.
+func ifMatchVersions(values []string) ([]int64, error) {
+	tags, wildcard := parseETags(values)
+	if wildcard || len(tags) == 0 {
+		return nil, nil
+	}
+	var versions []int64
+	for _, tag := range tags {
+		if version, ok := parseVersion(tag); ok {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, service.ErrVersionMismatch
+	}
+	return versions, nil
+}
+
+// ifNoneMatch reports whether an If-None-Match header matches the version,
+// using the weak comparison.
+func ifNoneMatch(values []string, version int64) bool {
+	tags, wildcard := parseETags(values)
+	if wildcard {
+		return true
+	}
+	for _, tag := range tags {
+		if v, ok := parseVersion(strings.TrimPrefix(tag, "W/")); ok && v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func parseVersion(tag string) (int64, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	return version, err == nil
+}
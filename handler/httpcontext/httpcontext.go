@@ -5,7 +5,10 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-const UserKey = "user"
+const (
+	UserKey      = "user"
+	RequestIDKey = "request_id"
+)
 
 // GetUserJWT get user response from context
 func GetUserJWT(c echo.Context) (*jwt.User, bool) {
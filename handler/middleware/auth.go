@@ -4,22 +4,22 @@ import (
 	"strings"
 
 	"github.com/SawitProRecruitment/UserService/handler/httpcontext"
-	"github.com/SawitProRecruitment/UserService/lib/errors"
 	"github.com/SawitProRecruitment/UserService/lib/jwt"
 	"github.com/labstack/echo/v4"
 )
 
-func Auth(c echo.Context) error {
+// Auth sets the user of the bearer token of the request, verified by tokens.
+func Auth(c echo.Context, tokens jwt.TokenService) error {
 	token := c.Request().Header.Get("Authorization")
 	splitToken := strings.Split(token, "Bearer")
 	if len(splitToken) < 2 {
-		return errors.NewForbiddenError("unauthorized")
+		return ErrUnauthorized
 	}
 
 	bearer := strings.Trim(splitToken[1], " ")
-	user, err := jwt.GetDataFromToken(bearer)
+	user, err := tokens.GetDataFromToken(bearer)
 	if err != nil {
-		return errors.NewForbiddenError("unauthorized")
+		return ErrUnauthorized
 	}
 	c.Set(httpcontext.UserKey, user)
 	return nil
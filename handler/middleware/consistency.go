@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// ReadYourWrites sends the reads of a request that follow one of its writes to
+// the primary database, so that replica lag never hides what the request just
+// wrote.
+func ReadYourWrites(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		c.SetRequest(req.WithContext(repository.WithReadYourWrites(req.Context())))
+		return next(c)
+	}
+}
@@ -0,0 +1,7 @@
+package middleware
+
+import "github.com/SawitProRecruitment/UserService/lib/errors"
+
+const CodeUnauthorized errors.Code = "UNAUTHORIZED"
+
+var ErrUnauthorized = errors.NewForbiddenError("unauthorized").WithCode(CodeUnauthorized)
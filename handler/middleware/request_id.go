@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/handler/httpcontext"
+	"github.com/SawitProRecruitment/UserService/lib/logger"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// validRequestID limits what a client may send as X-Request-ID, since it ends up in our logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestLogger accepts or generates an X-Request-ID, stores a logger tagged
+// with it in the request context and logs every request once it completes.
+func RequestLogger(base *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+
+			requestID := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID.MatchString(requestID) {
+				requestID = uuid.NewString()
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+			c.Set(httpcontext.RequestIDKey, requestID)
+
+			l := base.With(slog.String("request_id", requestID))
+			if sc := trace.SpanContextFromContext(req.Context()); sc.HasTraceID() {
+				l = l.With(slog.String("trace_id", sc.TraceID().String()))
+			}
+			c.SetRequest(req.WithContext(logger.WithContext(req.Context(), l)))
+
+			// Let the error handler write the response first so the status is final.
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			l.Info("request completed",
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.Int("status", c.Response().Status),
+				slog.Duration("latency", time.Since(start)),
+			)
+			return nil
+		}
+	}
+}
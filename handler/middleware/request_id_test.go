@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/handler/httpcontext"
+	"github.com/SawitProRecruitment/UserService/lib/logger"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLogger(t *testing.T) {
+	t.Parallel()
+
+	// serve runs a request through RequestLogger, with a handler logging
+	// through the logger of the request context.
+	serve := func(t *testing.T, requestID string) (*httptest.ResponseRecorder, echo.Context, []map[string]any) {
+		var buf bytes.Buffer
+		req := httptest.NewRequest(http.MethodGet, "/url", nil)
+		if requestID != "" {
+			req.Header.Set(echo.HeaderXRequestID, requestID)
+		}
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		err := RequestLogger(logger.New(&buf, "info"))(func(c echo.Context) error {
+			logger.FromContext(c.Request().Context()).Info("handled")
+			return c.NoContent(http.StatusNoContent)
+		})(c)
+		require.NoError(t, err)
+
+		var lines []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var out map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &out))
+			lines = append(lines, out)
+		}
+		return rec, c, lines
+	}
+
+	t.Run("keeps the incoming request ID", func(t *testing.T) {
+		t.Parallel()
+		rec, c, lines := serve(t, "abc-123")
+
+		assert.Equal(t, "abc-123", rec.Header().Get(echo.HeaderXRequestID))
+		assert.Equal(t, "abc-123", c.Get(httpcontext.RequestIDKey))
+		require.Len(t, lines, 2)
+		for _, line := range lines {
+			assert.Equal(t, "abc-123", line["request_id"])
+		}
+	})
+
+	t.Run("generates a missing request ID", func(t *testing.T) {
+		t.Parallel()
+		rec, _, lines := serve(t, "")
+
+		requestID := rec.Header().Get(echo.HeaderXRequestID)
+		assert.NoError(t, uuid.Validate(requestID))
+		require.Len(t, lines, 2)
+		assert.Equal(t, requestID, lines[0]["request_id"])
+		assert.Equal(t, "handled", lines[0]["msg"])
+		assert.Equal(t, requestID, lines[1]["request_id"])
+		assert.Equal(t, float64(http.StatusNoContent), lines[1]["status"])
+	})
+
+	t.Run("replaces an invalid request ID", func(t *testing.T) {
+		t.Parallel()
+		rec, _, lines := serve(t, "not valid\n")
+
+		requestID := rec.Header().Get(echo.HeaderXRequestID)
+		assert.NoError(t, uuid.Validate(requestID))
+		assert.Equal(t, requestID, lines[0]["request_id"])
+	})
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// TraceResponse writes the current trace context back to the caller as a
+// `traceparent` response header, so clients can correlate their request with
+// our traces. It must be registered after the otelecho middleware.
+func TraceResponse(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		otel.GetTextMapPropagator().Inject(c.Request().Context(), propagation.HeaderCarrier(c.Response().Header()))
+		return next(c)
+	}
+}
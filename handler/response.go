@@ -1,11 +1,27 @@
 package handler
 
-import "github.com/SawitProRecruitment/UserService/service"
+import (
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/lib/validator"
+	"github.com/SawitProRecruitment/UserService/service"
+)
 
 type userData struct {
-	Id    int64  `json:"id"`
-	Name  string `json:"name,omitempty"`
-	Phone string `json:"phone,omitempty"`
+	Id            int64             `json:"id"`
+	Name          string            `json:"name,omitempty"`
+	Phone         string            `json:"phone,omitempty"`
+	Email         string            `json:"email,omitempty"`
+	EmailVerified *bool             `json:"email_verified,omitempty"`
+	DateOfBirth   string            `json:"date_of_birth,omitempty"`
+	AvatarURL     string            `json:"avatar_url,omitempty"`
+	Avatars       map[string]string `json:"avatars,omitempty"`
+	Locale        string            `json:"locale,omitempty"`
+	Timezone      string            `json:"timezone,omitempty"`
+}
+
+type avatarData struct {
+	Avatars map[string]string `json:"avatars"`
 }
 
 type userDataLogin struct {
@@ -34,9 +50,27 @@ func newSuccessGetByIDResponse(u *service.User) *responseWithData {
 			Message: "Successfully get user data!",
 		},
 		Data: userData{
-			Id:    u.Id,
-			Name:  u.Name,
-			Phone: u.Phone,
+			Id:            u.Id,
+			Name:          u.Name,
+			Phone:         u.Phone,
+			Email:         u.Email,
+			EmailVerified: &u.EmailVerified,
+			DateOfBirth:   formatDate(u.DateOfBirth),
+			AvatarURL:     u.AvatarURL,
+			Avatars:       u.Avatars,
+			Locale:        u.Locale,
+			Timezone:      u.Timezone,
+		},
+	}
+}
+
+func newSuccessUploadAvatarResponse(u *service.ResponseUploadAvatar) *responseWithData {
+	return &responseWithData{
+		baseResponse: baseResponse{
+			Message: "Successfully upload avatar!",
+		},
+		Data: avatarData{
+			Avatars: u.Avatars,
 		},
 	}
 }
@@ -63,3 +97,10 @@ func newSuccessLogin(u *service.ResponseLogin) *responseWithData {
 		},
 	}
 }
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(validator.DateLayout)
+}
@@ -1,19 +1,31 @@
 package handler
 
 import (
+	"github.com/SawitProRecruitment/UserService/lib/jwt"
 	"github.com/SawitProRecruitment/UserService/service"
 )
 
 type Server struct {
-	Service service.ServiceInterface
+	Service       service.ServiceInterface
+	TokenService  jwt.TokenService
+	AvatarMaxSize int64
 }
 
 type NewServerOptions struct {
 	Service service.ServiceInterface
+	// TokenService verifies the bearer tokens of the authenticated endpoints.
+	TokenService jwt.TokenService
+	// AvatarMaxSize in bytes, defaults to service.DefaultAvatarMaxSize.
+	AvatarMaxSize int64
 }
 
 func NewServer(opts NewServerOptions) *Server {
+	if opts.AvatarMaxSize <= 0 {
+		opts.AvatarMaxSize = service.DefaultAvatarMaxSize
+	}
 	return &Server{
-		Service: opts.Service,
+		Service:       opts.Service,
+		TokenService:  opts.TokenService,
+		AvatarMaxSize: opts.AvatarMaxSize,
 	}
 }
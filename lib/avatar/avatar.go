@@ -0,0 +1,103 @@
+// Package avatar turns an uploaded picture into square JPEG thumbnails.
+// Re-encoding drops every metadata of the upload, EXIF included, after its
+// orientation has been applied to the pixels.
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"slices"
+
+	// Decoders of the accepted formats.
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrInvalidImage    = errors.New("invalid image")
+	ErrTooManyPixels   = errors.New("image dimensions too large")
+)
+
+// ContentTypes are the accepted upload types, detected from the content.
+var ContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// ContentType of every thumbnail.
+const ContentType = "image/jpeg"
+
+// MaxPixels bounds the decoded size of an upload, a small file can still
+// decode to a huge bitmap.
+const MaxPixels = 40_000_000
+
+const jpegQuality = 85
+
+type Size struct {
+	Name   string
+	Pixels int
+}
+
+// Sizes of the generated thumbnails, from the smallest.
+var Sizes = []Size{
+	{Name: "small", Pixels: 64},
+	{Name: "medium", Pixels: 256},
+	{Name: "large", Pixels: 512},
+}
+
+type Thumbnail struct {
+	Size Size
+	Data []byte
+}
+
+// Process decodes data and returns one thumbnail per entry of Sizes. The
+// picture is cropped to its centered square and transparent areas become
+// white.
+func Process(data []byte) ([]Thumbnail, error) {
+	if !slices.Contains(ContentTypes, http.DetectContentType(data)) {
+		return nil, ErrUnsupportedType
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	img = orient(img, exifOrientation(data))
+
+	thumbnails := make([]Thumbnail, 0, len(Sizes))
+	for _, size := range Sizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, thumbnail(img, size.Pixels), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		thumbnails = append(thumbnails, Thumbnail{Size: size, Data: buf.Bytes()})
+	}
+	return thumbnails, nil
+}
+
+func thumbnail(img image.Image, pixels int) image.Image {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	src := image.Rect(x, y, x+side, y+side)
+
+	dst := image.NewRGBA(image.Rect(0, 0, pixels, pixels))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Over, nil)
+	return dst
+}
@@ -0,0 +1,135 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// withOrientation inserts an APP1 Exif segment holding only the orientation
+// tag right after the SOI marker of a JPEG.
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	out := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestProcess(t *testing.T) {
+	t.Parallel()
+
+	t.Run("square thumbnails of every size", func(t *testing.T) {
+		img := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+		thumbnails, err := Process(encodePNG(t, img))
+		assert.NoError(t, err)
+		assert.Len(t, thumbnails, len(Sizes))
+		for i, thumbnail := range thumbnails {
+			assert.Equal(t, Sizes[i], thumbnail.Size)
+			decoded, format, err := image.Decode(bytes.NewReader(thumbnail.Data))
+			assert.NoError(t, err)
+			assert.Equal(t, "jpeg", format)
+			assert.Equal(t, image.Rect(0, 0, Sizes[i].Pixels, Sizes[i].Pixels), decoded.Bounds())
+		}
+	})
+
+	t.Run("transparency becomes white", func(t *testing.T) {
+		img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+		thumbnails, err := Process(encodePNG(t, img))
+		assert.NoError(t, err)
+		decoded, _, _ := image.Decode(bytes.NewReader(thumbnails[0].Data))
+		r, g, b, _ := decoded.At(32, 32).RGBA()
+		assert.Greater(t, r, uint32(0xF000))
+		assert.Greater(t, g, uint32(0xF000))
+		assert.Greater(t, b, uint32(0xF000))
+	})
+
+	t.Run("exif is stripped", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil))
+		data := withOrientation(buf.Bytes(), 6)
+		assert.Equal(t, 6, exifOrientation(data))
+
+		thumbnails, err := Process(data)
+		assert.NoError(t, err)
+		for _, thumbnail := range thumbnails {
+			assert.NotContains(t, string(thumbnail.Data), "Exif")
+		}
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		_, err := Process([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+		assert.ErrorIs(t, err, ErrUnsupportedType)
+	})
+
+	t.Run("invalid image", func(t *testing.T) {
+		data := encodePNG(t, image.NewGray(image.Rect(0, 0, 8, 8)))
+		_, err := Process(data[:len(data)/2])
+		assert.ErrorIs(t, err, ErrInvalidImage)
+	})
+
+	t.Run("too many pixels", func(t *testing.T) {
+		// Only the header is read, the pixel data does not need to be there.
+		data := encodePNG(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+		binary.BigEndian.PutUint32(data[16:], 10_000)
+		binary.BigEndian.PutUint32(data[20:], 10_000)
+		binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+		_, err := Process(data)
+		assert.ErrorIs(t, err, ErrTooManyPixels)
+	})
+}
+
+func TestOrient(t *testing.T) {
+	t.Parallel()
+
+	// 2x1: a red pixel followed by a blue one.
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+	blue := color.RGBA{B: 0xFF, A: 0xFF}
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, red)
+	img.Set(1, 0, blue)
+
+	t.Run("no orientation", func(t *testing.T) {
+		assert.Equal(t, image.Image(img), orient(img, 1))
+	})
+
+	t.Run("rotate 90 clockwise", func(t *testing.T) {
+		out := orient(img, 6)
+		assert.Equal(t, image.Rect(0, 0, 1, 2), out.Bounds())
+		assert.Equal(t, color.RGBAModel.Convert(red), out.At(0, 0))
+		assert.Equal(t, color.RGBAModel.Convert(blue), out.At(0, 1))
+	})
+
+	t.Run("rotate 90 counter clockwise", func(t *testing.T) {
+		out := orient(img, 8)
+		assert.Equal(t, color.RGBAModel.Convert(blue), out.At(0, 0))
+		assert.Equal(t, color.RGBAModel.Convert(red), out.At(0, 1))
+	})
+
+	t.Run("mirror", func(t *testing.T) {
+		out := orient(img, 2)
+		assert.Equal(t, color.RGBAModel.Convert(blue), out.At(0, 0))
+		assert.Equal(t, color.RGBAModel.Convert(red), out.At(1, 0))
+	})
+}
@@ -0,0 +1,37 @@
+// This file contains the interface every blob storage backend implements.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Object is a stored blob, the caller has to close Body.
+type Object struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// BlobStore stores immutable blobs under slash separated keys such as
+// "12/abc-small.jpg". Keys must not be absolute nor contain "..".
+//
+//go:generate mockgen -source interfaces.go -destination interfaces.mock.gen.go -package=blob
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Get returns ErrNotFound when there is no blob under key.
+	Get(ctx context.Context, key string) (*Object, error)
+	// Delete does not fail when there is no blob under key.
+	Delete(ctx context.Context, key string) error
+	// URL is where clients download the blob from, either a route of this
+	// service or the store itself, e.g. a CDN in front of a bucket.
+	URL(key string) string
+}
@@ -0,0 +1,71 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir, "http://localhost/v1/avatars/")
+	assert.NoError(t, err)
+
+	t.Run("put then get", func(t *testing.T) {
+		err := store.Put(ctx, "1/abc-small.jpg", strings.NewReader("jpeg"), "image/jpeg")
+		assert.NoError(t, err)
+
+		object, err := store.Get(ctx, "1/abc-small.jpg")
+		assert.NoError(t, err)
+		defer object.Body.Close()
+		body, _ := io.ReadAll(object.Body)
+		assert.Equal(t, "jpeg", string(body))
+		assert.Equal(t, "image/jpeg", object.ContentType)
+		assert.Equal(t, int64(4), object.Size)
+	})
+
+	t.Run("put replaces", func(t *testing.T) {
+		assert.NoError(t, store.Put(ctx, "2/a.jpg", strings.NewReader("old"), "image/jpeg"))
+		assert.NoError(t, store.Put(ctx, "2/a.jpg", strings.NewReader("new"), "image/jpeg"))
+
+		body, _ := os.ReadFile(filepath.Join(dir, "2", "a.jpg"))
+		assert.Equal(t, "new", string(body))
+		entries, _ := os.ReadDir(filepath.Join(dir, "2"))
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := store.Get(ctx, "1/missing.jpg")
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = store.Get(ctx, "1")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, store.Put(ctx, "3/a.jpg", strings.NewReader("jpeg"), "image/jpeg"))
+		assert.NoError(t, store.Delete(ctx, "3/a.jpg"))
+		_, err := store.Get(ctx, "3/a.jpg")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, store.Delete(ctx, "3/a.jpg"))
+	})
+
+	t.Run("keys cannot escape the directory", func(t *testing.T) {
+		for _, key := range []string{"", ".", "../a.jpg", "1/../../a.jpg", "/etc/passwd", `1\a.jpg`} {
+			_, err := store.Get(ctx, key)
+			assert.ErrorIs(t, err, ErrInvalidKey, key)
+			assert.ErrorIs(t, store.Put(ctx, key, strings.NewReader(""), ""), ErrInvalidKey, key)
+			assert.ErrorIs(t, store.Delete(ctx, key), ErrInvalidKey, key)
+		}
+	})
+
+	t.Run("url", func(t *testing.T) {
+		assert.Equal(t, "http://localhost/v1/avatars/1/abc-small.jpg", store.URL("1/abc-small.jpg"))
+	})
+}
@@ -0,0 +1,19 @@
+// This file contains the interface every cache backend implements.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores values for a while under string keys. Values are bytes so that
+// a backend may be shared by several processes, e.g. Redis.
+//
+//go:generate mockgen -source interfaces.go -destination interfaces.mock.gen.go -package=cache
+type Cache interface {
+	// Get reports whether there is an unexpired value under key.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete does not fail when there is no value under a key.
+	Delete(ctx context.Context, keys ...string) error
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type lru struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// recent has the most recently used entry first.
+	recent *list.List
+	now    func() time.Time
+}
+
+type entry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+type LRUOptions struct {
+	// Size is the number of values kept, the least recently used one is
+	// evicted to make room for a new one.
+	Size int
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewLRU returns an in-process Cache, safe for concurrent use.
+func NewLRU(opts LRUOptions) Cache {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &lru{
+		size:    opts.Size,
+		entries: map[string]*list.Element{},
+		recent:  list.New(),
+		now:     opts.Now,
+	}
+}
+
+func (c *lru) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := element.Value.(*entry)
+	if !c.now().Before(e.expiresAt) {
+		c.remove(element)
+		return nil, false, nil
+	}
+	c.recent.MoveToFront(element)
+	return e.value, true, nil
+}
+
+func (c *lru) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.size <= 0 {
+		return nil
+	}
+	expiresAt := c.now().Add(ttl)
+	if element, ok := c.entries[key]; ok {
+		e := element.Value.(*entry)
+		e.value, e.expiresAt = value, expiresAt
+		c.recent.MoveToFront(element)
+		return nil
+	}
+	for c.recent.Len() >= c.size {
+		c.remove(c.recent.Back())
+	}
+	c.entries[key] = c.recent.PushFront(&entry{key: key, value: value, expiresAt: expiresAt})
+	return nil
+}
+
+func (c *lru) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := c.entries[key]; ok {
+			c.remove(element)
+		}
+	}
+	return nil
+}
+
+func (c *lru) remove(element *list.Element) {
+	c.recent.Remove(element)
+	delete(c.entries, element.Value.(*entry).key)
+}
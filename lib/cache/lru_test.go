@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	now := time.Now()
+	newLRU := func(size int) Cache {
+		return NewLRU(LRUOptions{Size: size, Now: func() time.Time { return now }})
+	}
+	get := func(c Cache, key string) string {
+		value, ok, err := c.Get(ctx, key)
+		assert.NoError(t, err)
+		if !ok {
+			return "<miss>"
+		}
+		return string(value)
+	}
+
+	t.Run("set then get", func(t *testing.T) {
+		c := newLRU(2)
+		assert.Equal(t, "<miss>", get(c, "a"))
+		assert.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+		assert.Equal(t, "1", get(c, "a"))
+		assert.NoError(t, c.Set(ctx, "a", []byte("2"), time.Minute))
+		assert.Equal(t, "2", get(c, "a"))
+	})
+
+	t.Run("evicts the least recently used", func(t *testing.T) {
+		c := newLRU(2)
+		c.Set(ctx, "a", []byte("1"), time.Minute)
+		c.Set(ctx, "b", []byte("2"), time.Minute)
+		get(c, "a")
+		c.Set(ctx, "c", []byte("3"), time.Minute)
+		assert.Equal(t, "1", get(c, "a"))
+		assert.Equal(t, "<miss>", get(c, "b"))
+		assert.Equal(t, "3", get(c, "c"))
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		now := now
+		c := NewLRU(LRUOptions{Size: 2, Now: func() time.Time { return now }})
+		c.Set(ctx, "a", []byte("1"), time.Minute)
+		now = now.Add(59 * time.Second)
+		assert.Equal(t, "1", get(c, "a"))
+		now = now.Add(time.Second)
+		assert.Equal(t, "<miss>", get(c, "a"))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		c := newLRU(2)
+		c.Set(ctx, "a", []byte("1"), time.Minute)
+		c.Set(ctx, "b", []byte("2"), time.Minute)
+		assert.NoError(t, c.Delete(ctx, "a", "b", "missing"))
+		assert.Equal(t, "<miss>", get(c, "a"))
+		assert.Equal(t, "<miss>", get(c, "b"))
+	})
+
+	t.Run("size 0 keeps nothing", func(t *testing.T) {
+		c := newLRU(0)
+		c.Set(ctx, "a", []byte("1"), time.Minute)
+		assert.Equal(t, "<miss>", get(c, "a"))
+	})
+}
@@ -2,68 +2,163 @@ package errors
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
 
+	"github.com/SawitProRecruitment/UserService/lib/i18n"
+	"github.com/SawitProRecruitment/UserService/lib/logger"
+
 	"github.com/labstack/echo/v4"
 	"gopkg.in/go-playground/validator.v9"
 )
 
-func NewValidatorError(err error) map[string]interface{} {
+func NewValidatorError(err error, locale string) map[string]interface{} {
 	e := make(map[string]interface{})
 	errs := err.(validator.ValidationErrors)
 	for _, v := range errs {
-		var errMessage error
+		key := "validation." + v.Tag()
 		switch v.Tag() {
-		case "required":
-			errMessage = fmt.Errorf("Field '%s' must be filled", v.Field())
-		case "customPassword":
-			errMessage = fmt.Errorf("Field '%s'must be minimum 6 characters and maximum 64 characters, containing at least 1 capital characters AND 1 number AND 1 special (nonalpha-numeric) characters.", v.Field())
-		case "customPhone":
-			errMessage = fmt.Errorf("Field '%s' must start with the Indonesia country code “+62” and must be at minimum 10 characters and maximum 13 characters.", v.Field())
-		case "min":
-			errMessage = fmt.Errorf("Field '%s' must greater than %s", v.Field(), v.Param())
-		case "max":
-			errMessage = fmt.Errorf("Field '%s' must less than %s", v.Field(), v.Param())
+		case "required", "required_without", "customPhone", "customDateOfBirth", "customLocale", "customTimezone",
+			"min", "max", "email", "url":
 		default:
-			errMessage = fmt.Errorf("Field '%s': '%v' must satisfy '%s' '%v' criteria", v.Field(), v.Value(), v.Tag(), v.Param())
+			key = "validation.default"
 		}
-		e[v.Field()] = fmt.Sprintf("%v", errMessage)
+		e[v.Field()] = i18n.Translate(locale, key, "", map[string]string{
+			"field": v.Field(),
+			"param": v.Param(),
+			"tag":   v.Tag(),
+			"value": fmt.Sprintf("%v", v.Value()),
+		})
 	}
 	return e
 }
 
+// HTTPErrorHandlerOptions .
+type HTTPErrorHandlerOptions struct {
+	// ProblemTypeBase is the absolute URI the type of problem details starts
+	// with, DefaultProblemTypeBase when empty.
+	ProblemTypeBase string
+}
+
+// NewHTTPErrorHandler returns the echo.HTTPErrorHandler writing errors as
+// ErrorResponse, or as Problem when the client asks for it.
+func NewHTTPErrorHandler(opts HTTPErrorHandlerOptions) echo.HTTPErrorHandler {
+	if opts.ProblemTypeBase == "" {
+		opts.ProblemTypeBase = DefaultProblemTypeBase
+	}
+	return func(err error, c echo.Context) {
+		handleHTTPError(opts, err, c)
+	}
+}
+
+// CustomHTTPErrorHandler is the handler of NewHTTPErrorHandler with the
+// default options.
 func CustomHTTPErrorHandler(err error, c echo.Context) {
+	handleHTTPError(HTTPErrorHandlerOptions{ProblemTypeBase: DefaultProblemTypeBase}, err, c)
+}
+
+func handleHTTPError(opts HTTPErrorHandlerOptions, err error, c echo.Context) {
+	locale := i18n.Match(c.Request().Header.Get("Accept-Language"))
 	code := http.StatusInternalServerError
+	errorCode := CodeInternal
 	message := "Something went wrong"
 	var data any
-	if he, ok := err.(*echo.HTTPError); ok {
+	var params map[string]string
+
+	var (
+		he        *echo.HTTPError
+		validErrs validator.ValidationErrors
+		appErr    *Error
+	)
+	switch {
+	case As(err, &he):
 		code = he.Code
-	}
-	switch v := err.(type) {
-	case *echo.HTTPError:
-		code = v.Code
-		data = v.Message
-	case validator.ValidationErrors:
+		errorCode = codeFromStatus(code)
+		data = he.Message
+	case As(err, &validErrs):
 		code = http.StatusBadRequest
+		errorCode = CodeValidation
 		message = "Validation error"
-		data = NewValidatorError(err)
-	case baseError:
-		code = v.code
-		message = v.Error()
-	default:
-		message = v.Error()
+		data = NewValidatorError(validErrs, locale)
+	case As(err, &appErr):
+		code = appErr.Status
+		errorCode = appErr.Code
+		message = appErr.Message
+		params = appErr.Params
+	}
+
+	// Internal details only go to the logs, clients get a generic message and
+	// the request id to quote when reporting the problem.
+	if code >= http.StatusInternalServerError {
+		message = "Something went wrong"
+		data = nil
+		logger.FromContext(c.Request().Context()).Error("request failed",
+			slog.Int("status", code),
+			slog.String("error_code", string(errorCode)),
+			slog.String("error", err.Error()),
+		)
+	}
+	// The catalog is keyed by error code, message is only the fallback for
+	// codes it does not know. The message of an application error is kept
+	// over the text of a bare status code, which would tell the client less.
+	if appErr == nil || code >= http.StatusInternalServerError || !isStatusCode(errorCode) {
+		message = i18n.Translate(locale, string(errorCode), message, params)
+	}
+	c.Response().Header().Set("Content-Language", locale)
+
+	if wantsProblem(c.Request().Header.Get(echo.HeaderAccept)) {
+		c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+		c.JSON(code, newProblem(opts.ProblemTypeBase, code, errorCode, message, data, c))
+		return
 	}
 	errResponse := ErrorResponse{
-		Code:    fmt.Sprintf("%d", code),
-		Message: message,
-		Data:    data,
+		Code:          fmt.Sprintf("%d", code),
+		ErrorCode:     errorCode,
+		Message:       message,
+		Data:          data,
+		CorrelationID: c.Response().Header().Get(echo.HeaderXRequestID),
 	}
 
 	c.JSON(code, errResponse)
 }
 
+// isStatusCode reports whether code only names an HTTP status, as
+// codeFromStatus returns.
+func isStatusCode(code Code) bool {
+	switch code {
+	case CodeBadRequest, CodeValidation, CodeForbidden, CodeNotFound, CodeConflict, CodeInternal,
+		CodePreconditionFailed, CodePayloadTooLarge, CodeUnsupportedMediaType:
+		return true
+	default:
+		return false
+	}
+}
+
+func codeFromStatus(status int) Code {
+	switch {
+	case status == http.StatusNotFound:
+		return CodeNotFound
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return CodeForbidden
+	case status == http.StatusConflict:
+		return CodeConflict
+	case status == http.StatusPreconditionFailed:
+		return CodePreconditionFailed
+	case status == http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case status == http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMediaType
+	case status >= http.StatusInternalServerError:
+		return CodeInternal
+	default:
+		return CodeBadRequest
+	}
+}
+
 type ErrorResponse struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-	Data    any    `json:"data,omitempty"`
+	Code          string `json:"code"`
+	ErrorCode     Code   `json:"error_code"`
+	Message       string `json:"message"`
+	Data          any    `json:"data,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
 }
@@ -0,0 +1,236 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+func serve(err error, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/url", nil)
+	if accept != "" {
+		req.Header.Set(echo.HeaderAccept, accept)
+	}
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.Response().Header().Set(echo.HeaderXRequestID, "request-id")
+
+	CustomHTTPErrorHandler(err, c)
+	return rec
+}
+
+func serveError(err error) (*httptest.ResponseRecorder, ErrorResponse) {
+	rec := serve(err, "")
+
+	var res ErrorResponse
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	return rec, res
+}
+
+func serveProblem(err error, accept string) (*httptest.ResponseRecorder, Problem) {
+	rec := serve(err, accept)
+
+	var res Problem
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	return rec, res
+}
+
+func TestCustomHTTPErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("hides internal error details", func(t *testing.T) {
+		rec, res := serveError(fmt.Errorf(`pq: duplicate key value violates unique constraint "users_phone_key"`))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "users_phone_key")
+		assert.Equal(t, CodeInternal, res.ErrorCode)
+		assert.Equal(t, "Something went wrong", res.Message)
+		assert.Equal(t, "request-id", res.CorrelationID)
+	})
+
+	t.Run("hides wrapped internal error details", func(t *testing.T) {
+		rec, res := serveError(NewInternalError(fmt.Errorf("dial tcp 10.0.0.1:5432: connection refused")))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "10.0.0.1")
+		assert.Equal(t, CodeInternal, res.ErrorCode)
+	})
+
+	t.Run("exposes typed error code and message", func(t *testing.T) {
+		err := fmt.Errorf("update profile: %w", NewConflictError("phone number already used").WithCode("PHONE_ALREADY_USED"))
+		rec, res := serveError(err)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "409", res.Code)
+		assert.Equal(t, Code("PHONE_ALREADY_USED"), res.ErrorCode)
+		assert.Equal(t, "phone number already used", res.Message)
+	})
+}
+
+func TestCustomHTTPErrorHandler_Problem(t *testing.T) {
+	t.Parallel()
+
+	t.Run("keeps legacy format by default", func(t *testing.T) {
+		rec := serve(NewNotFoundError("user not found"), "application/json, */*")
+
+		assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+	})
+
+	t.Run("keeps legacy format when json is preferred", func(t *testing.T) {
+		rec := serve(NewNotFoundError("user not found"), "application/json, application/problem+json;q=0.5")
+
+		assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+	})
+
+	t.Run("emits problem details when requested", func(t *testing.T) {
+		rec, res := serveProblem(NewNotFoundError("user not found").WithCode("USER_NOT_FOUND"), "application/problem+json")
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, Problem{
+			Type:          "about:blank",
+			Title:         "Not Found",
+			Status:        http.StatusNotFound,
+			Detail:        "user not found",
+			Instance:      "/url",
+			Code:          "USER_NOT_FOUND",
+			CorrelationID: "request-id",
+		}, res)
+	})
+
+	t.Run("lists validation errors", func(t *testing.T) {
+		v := validator.New()
+		err := v.Struct(struct {
+			Name  string `validate:"required"`
+			Phone string `validate:"required"`
+		}{})
+		rec, res := serveProblem(err, "application/problem+json")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "urn:sawitpro:user-service:problem:validation-error", res.Type)
+		assert.Equal(t, CodeValidation, res.Code)
+		assert.Equal(t, []InvalidParam{
+			{Field: "Name", Detail: "Field 'Name' must be filled"},
+			{Field: "Phone", Detail: "Field 'Phone' must be filled"},
+		}, res.Errors)
+	})
+
+	t.Run("hides internal error details", func(t *testing.T) {
+		rec, res := serveProblem(fmt.Errorf("pq: relation \"users\" does not exist"), "application/problem+json")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "does not exist")
+		assert.Equal(t, "about:blank", res.Type)
+	})
+
+	t.Run("prefixes the type with the configured base", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/url", nil)
+		req.Header.Set(echo.HeaderAccept, MIMEApplicationProblemJSON)
+		rec := httptest.NewRecorder()
+		handler := NewHTTPErrorHandler(HTTPErrorHandlerOptions{ProblemTypeBase: "https://docs.example.com/problems/"})
+
+		handler(NewBadRequestError("invalid").WithCode(CodeValidation), echo.New().NewContext(req, rec))
+
+		var res Problem
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		assert.Equal(t, "https://docs.example.com/problems/validation-error", res.Type)
+	})
+}
+
+func TestCustomHTTPErrorHandler_Locale(t *testing.T) {
+	t.Parallel()
+
+	t.Run("translates message by error code", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/url", nil)
+		req.Header.Set("Accept-Language", "id-ID,id;q=0.9,en;q=0.8")
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		CustomHTTPErrorHandler(NewConflictError("phone number already used").WithCode("PHONE_ALREADY_USED"), c)
+
+		var res ErrorResponse
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		assert.Equal(t, "nomor telepon sudah digunakan", res.Message)
+		assert.Equal(t, "id", rec.Header().Get("Content-Language"))
+	})
+
+	t.Run("keeps the message of a bare status code", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/url", nil)
+		req.Header.Set("Accept-Language", "id")
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		CustomHTTPErrorHandler(NewConflictError("name already taken"), c)
+
+		var res ErrorResponse
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		assert.Equal(t, CodeConflict, res.ErrorCode)
+		assert.Equal(t, "name already taken", res.Message)
+	})
+
+	t.Run("translates the status of echo errors", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/url", nil)
+		req.Header.Set("Accept-Language", "id")
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		CustomHTTPErrorHandler(echo.ErrNotFound, c)
+
+		var res ErrorResponse
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		assert.Equal(t, "Data tidak ditemukan", res.Message)
+	})
+
+	t.Run("fills message params", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/url", nil)
+		req.Header.Set("Accept-Language", "id")
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		err := NewBadRequestError("password too short").WithCode("PASSWORD_TOO_SHORT").
+			WithParams("password must be at least 8 characters", map[string]string{"min": "8"})
+		CustomHTTPErrorHandler(err, c)
+
+		var res ErrorResponse
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		assert.Equal(t, "kata sandi minimal 8 karakter", res.Message)
+	})
+
+	t.Run("translates validation errors", func(t *testing.T) {
+		err := validator.New().Struct(struct {
+			Name string `validate:"required"`
+		}{})
+
+		data := NewValidatorError(err, "id")
+
+		assert.Equal(t, map[string]interface{}{"Name": "Kolom 'Name' wajib diisi"}, data)
+	})
+}
+
+func TestError_Is(t *testing.T) {
+	t.Parallel()
+
+	t.Run("matches by code through wrapping", func(t *testing.T) {
+		sentinel := NewNotFoundError("user not found").WithCode("USER_NOT_FOUND")
+		err := fmt.Errorf("get user: %w", sentinel)
+
+		assert.True(t, Is(err, sentinel))
+		assert.False(t, Is(err, NewNotFoundError("user not found")))
+	})
+
+	t.Run("unwraps the cause", func(t *testing.T) {
+		cause := fmt.Errorf("connection refused")
+		err := NewInternalError(cause)
+
+		assert.True(t, Is(err, cause))
+		var appErr *Error
+		assert.True(t, As(err, &appErr))
+		assert.Equal(t, http.StatusInternalServerError, appErr.Status)
+	})
+}
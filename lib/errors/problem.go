@@ -0,0 +1,94 @@
+package errors
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MIMEApplicationProblemJSON is the RFC 7807 media type.
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// DefaultProblemTypeBase prefixes the type URI of the problems that are not
+// about:blank when HTTPErrorHandlerOptions leaves it empty.
+const DefaultProblemTypeBase = "urn:sawitpro:user-service:problem:"
+
+// Problem is an RFC 7807 problem details document. Code, CorrelationID and
+// Errors are extension members.
+type Problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	Code          Code           `json:"code"`
+	CorrelationID string         `json:"correlation_id,omitempty"`
+	Errors        []InvalidParam `json:"errors,omitempty"`
+}
+
+// InvalidParam describes a single validation failure.
+type InvalidParam struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// wantsProblem reports whether the Accept header prefers problem+json over
+// plain JSON. Clients that do not ask for it keep getting ErrorResponse.
+func wantsProblem(accept string) bool {
+	var problemQ, jsonQ float64
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		q := 1.0
+		for _, p := range params[1:] {
+			k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if ok && strings.EqualFold(k, "q") {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		switch mediaType {
+		case MIMEApplicationProblemJSON:
+			problemQ = max(problemQ, q)
+		case echo.MIMEApplicationJSON:
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return problemQ > 0 && problemQ >= jsonQ
+}
+
+func newProblem(typeBase string, status int, code Code, message string, data any, c echo.Context) Problem {
+	p := Problem{
+		Type:          problemType(typeBase, code),
+		Title:         http.StatusText(status),
+		Status:        status,
+		Detail:        message,
+		Instance:      c.Request().URL.Path,
+		Code:          code,
+		CorrelationID: c.Response().Header().Get(echo.HeaderXRequestID),
+	}
+	if detail, ok := data.(string); ok {
+		p.Detail = detail
+	}
+	if fields, ok := data.(map[string]interface{}); ok && code == CodeValidation {
+		for field, detail := range fields {
+			p.Errors = append(p.Errors, InvalidParam{Field: field, Detail: detail.(string)})
+		}
+		sort.Slice(p.Errors, func(i, j int) bool { return p.Errors[i].Field < p.Errors[j].Field })
+	}
+	return p
+}
+
+// problemType is the type of validation errors, the only problems carrying
+// more than their status. Any other problem is about:blank, whose title is the
+// HTTP status text, as RFC 7807 defines it.
+func problemType(base string, code Code) string {
+	if code == CodeValidation {
+		return base + "validation-error"
+	}
+	return "about:blank"
+}
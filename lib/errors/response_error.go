@@ -1,38 +1,127 @@
 package errors
 
 import (
+	stderrors "errors"
 	"net/http"
 	"strings"
 )
 
-type baseError struct {
-	code    int
-	message string
+// Code is a stable, machine-readable error identifier. Unlike the HTTP status
+// it is specific to the failure, so clients can branch on it safely.
+type Code string
+
+const (
+	CodeBadRequest Code = "BAD_REQUEST"
+	CodeValidation Code = "VALIDATION_ERROR"
+	CodeForbidden  Code = "FORBIDDEN"
+	CodeNotFound   Code = "NOT_FOUND"
+	CodeConflict   Code = "CONFLICT"
+	CodeInternal   Code = "INTERNAL_ERROR"
+
+	CodePreconditionFailed   Code = "PRECONDITION_FAILED"
+	CodePayloadTooLarge      Code = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMediaType Code = "UNSUPPORTED_MEDIA_TYPE"
+)
+
+// Error is an error whose message is safe to show to clients. The wrapped
+// cause, if any, is only meant for logs.
+type Error struct {
+	Status  int
+	Code    Code
+	Message string
+	// Params fill the {placeholders} of the translated message.
+	Params map[string]string
+	Err    error
+}
+
+func newError(status int, code Code, msg string) *Error {
+	return &Error{
+		Status:  status,
+		Code:    code,
+		Message: msg,
+	}
 }
 
-func newBaseError(code int, msg string) baseError {
-	return baseError{
-		code:    code,
-		message: msg,
+func (err *Error) Error() string {
+	if err.Err != nil {
+		return strings.ToLower(err.Message) + ": " + err.Err.Error()
 	}
+	return strings.ToLower(err.Message)
+}
+
+// Unwrap returns the underlying cause.
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+// Is reports whether target is an *Error with the same code.
+func (err *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == err.Code
+}
+
+// WithCode returns a copy of err with a more specific code.
+func (err *Error) WithCode(code Code) *Error {
+	e := *err
+	e.Code = code
+	return &e
+}
+
+// WithParams returns a copy of err with message params, message is the
+// fallback with the params already filled in.
+func (err *Error) WithParams(message string, params map[string]string) *Error {
+	e := *err
+	e.Message = message
+	e.Params = params
+	return &e
+}
+
+// Wrap returns a copy of err carrying cause.
+func (err *Error) Wrap(cause error) *Error {
+	e := *err
+	e.Err = cause
+	return &e
+}
+
+func NewNotFoundError(message string) *Error {
+	return newError(http.StatusNotFound, CodeNotFound, message)
+}
+
+func NewForbiddenError(message string) *Error {
+	return newError(http.StatusForbidden, CodeForbidden, message)
+}
+
+func NewBadRequestError(message string) *Error {
+	return newError(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+func NewConflictError(message string) *Error {
+	return newError(http.StatusConflict, CodeConflict, message)
+}
+
+func NewPreconditionFailedError(message string) *Error {
+	return newError(http.StatusPreconditionFailed, CodePreconditionFailed, message)
 }
 
-func (err baseError) Error() string {
-	return strings.ToLower(err.message)
+func NewPayloadTooLargeError(message string) *Error {
+	return newError(http.StatusRequestEntityTooLarge, CodePayloadTooLarge, message)
 }
 
-func NewNotFoundError(message string) baseError {
-	return newBaseError(http.StatusNotFound, message)
+func NewUnsupportedMediaTypeError(message string) *Error {
+	return newError(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, message)
 }
 
-func NewForbiddenError(message string) baseError {
-	return newBaseError(http.StatusForbidden, message)
+// NewInternalError wraps cause into an error whose details never reach the client.
+func NewInternalError(cause error) *Error {
+	return newError(http.StatusInternalServerError, CodeInternal, "something went wrong").Wrap(cause)
 }
 
-func NewBadRequestError(message string) baseError {
-	return newBaseError(http.StatusBadRequest, message)
+// Is is errors.Is from the standard library.
+func Is(err, target error) bool {
+	return stderrors.Is(err, target)
 }
 
-func NewConflictError(message string) baseError {
-	return newBaseError(http.StatusConflict, message)
+// As is errors.As from the standard library.
+func As(err error, target any) bool {
+	return stderrors.As(err, target)
 }
@@ -0,0 +1,90 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is used when the client does not ask for a supported locale.
+const DefaultLocale = "en"
+
+// Every locales/<locale>.json file is a catalog, adding a locale only needs a new file.
+//
+//go:embed locales/*.json
+var files embed.FS
+
+var (
+	catalogs = map[string]map[string]string{}
+	locales  []string
+	matcher  language.Matcher
+)
+
+func init() {
+	entries, err := files.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		b, err := files.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(b, &messages); err != nil {
+			panic(fmt.Errorf("i18n: invalid catalog %s: %w", entry.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+
+	// The default locale goes first, it is what the matcher falls back to.
+	for locale := range catalogs {
+		if locale != DefaultLocale {
+			locales = append(locales, locale)
+		}
+	}
+	sort.Strings(locales)
+	locales = append([]string{DefaultLocale}, locales...)
+
+	tags := make([]language.Tag, len(locales))
+	for i, locale := range locales {
+		tags[i] = language.MustParse(locale)
+	}
+	matcher = language.NewMatcher(tags)
+}
+
+// Match returns the supported locale that best fits an Accept-Language header.
+func Match(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return locales[index]
+}
+
+// Translate returns the message for key in locale, falling back to the default
+// locale and then to fallback. Placeholders like {field} are replaced by args.
+func Translate(locale, key, fallback string, args map[string]string) string {
+	message, ok := catalogs[locale][key]
+	if !ok {
+		message, ok = catalogs[DefaultLocale][key]
+	}
+	if !ok {
+		message = fallback
+	}
+
+	replacements := make([]string, 0, len(args)*2)
+	for k, v := range args {
+		replacements = append(replacements, "{"+k+"}", v)
+	}
+	return strings.NewReplacer(replacements...).Replace(message)
+}
@@ -0,0 +1,47 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"":                         "en",
+		"id":                       "id",
+		"id-ID,id;q=0.9,en;q=0.8":  "id",
+		"en-US,en;q=0.9,id;q=0.8":  "en",
+		"fr-FR":                    "en",
+		"fr-FR,id;q=0.5":           "id",
+		"not a language header!!!": "en",
+	}
+	for header, expected := range cases {
+		assert.Equal(t, expected, Match(header), header)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("replaces placeholders", func(t *testing.T) {
+		message := Translate("id", "validation.min", "", map[string]string{"field": "Name", "param": "3"})
+		assert.Equal(t, "Kolom 'Name' harus lebih besar dari 3", message)
+	})
+
+	t.Run("falls back to default locale then fallback", func(t *testing.T) {
+		assert.Equal(t, "user not found", Translate("fr", "USER_NOT_FOUND", "", nil))
+		assert.Equal(t, "fallback", Translate("id", "UNKNOWN_CODE", "fallback", nil))
+	})
+
+	t.Run("every catalog has the default locale keys", func(t *testing.T) {
+		for locale, messages := range catalogs {
+			for key := range catalogs[DefaultLocale] {
+				_, ok := messages[key]
+				assert.True(t, ok, "%s is missing %s", locale, key)
+			}
+		}
+	})
+}
@@ -0,0 +1,11 @@
+// This file contains the interface of the service issuing access tokens.
+package jwt
+
+//go:generate mockgen -source interfaces.go -destination interfaces.mock.gen.go -package=jwt
+type TokenService interface {
+	// GenerateToken returns a signed token carrying user.
+	GenerateToken(user User) (*string, error)
+	// GetDataFromToken returns the user of a token, or an error when it is
+	// not signed with the key of the service or has expired.
+	GetDataFromToken(token string) (*User, error)
+}
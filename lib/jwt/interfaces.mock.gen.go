@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: lib/jwt/interfaces.go
+
+// Package jwt is a generated GoMock package.
+package jwt
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockTokenService is a mock of TokenService interface.
+type MockTokenService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenServiceMockRecorder
+}
+
+// MockTokenServiceMockRecorder is the mock recorder for MockTokenService.
+type MockTokenServiceMockRecorder struct {
+	mock *MockTokenService
+}
+
+// NewMockTokenService creates a new mock instance.
+func NewMockTokenService(ctrl *gomock.Controller) *MockTokenService {
+	mock := &MockTokenService{ctrl: ctrl}
+	mock.recorder = &MockTokenServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenService) EXPECT() *MockTokenServiceMockRecorder {
+	return m.recorder
+}
+
+// GenerateToken mocks base method.
+func (m *MockTokenService) GenerateToken(user User) (*string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateToken", user)
+	ret0, _ := ret[0].(*string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateToken indicates an expected call of GenerateToken.
+func (mr *MockTokenServiceMockRecorder) GenerateToken(user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateToken", reflect.TypeOf((*MockTokenService)(nil).GenerateToken), user)
+}
+
+// GetDataFromToken mocks base method.
+func (m *MockTokenService) GetDataFromToken(token string) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDataFromToken", token)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDataFromToken indicates an expected call of GetDataFromToken.
+func (mr *MockTokenServiceMockRecorder) GetDataFromToken(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataFromToken", reflect.TypeOf((*MockTokenService)(nil).GetDataFromToken), token)
+}
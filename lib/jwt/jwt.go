@@ -1,22 +1,18 @@
 package jwt
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
-	"github.com/SawitProRecruitment/UserService/config"
-	"github.com/SawitProRecruitment/UserService/config/env"
-
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
-var cfg *config.Config
+// DefaultTTL is how long tokens are valid by default.
+const DefaultTTL = 24 * time.Hour
 
-func init() {
-	envConfig := env.New()
-	config.Init(envConfig)
-	cfg = config.Load()
-}
+// ErrNoPrivateKey is returned by a service whose key is empty.
+var ErrNoPrivateKey = errors.New("jwt: no private key")
 
 // MyClaims .
 type MyClaims struct {
@@ -30,17 +26,55 @@ type User struct {
 	Phone string `json:"phone"`
 }
 
+// Options .
+type Options struct {
+	// PrivateKey returns the HMAC key tokens are signed and verified with. It
+	// is called for each token, so that a rotated key is used at once.
+	PrivateKey func() string
+	// Issuer is the iss claim of the tokens.
+	Issuer string
+	// TTL defaults to DefaultTTL.
+	TTL time.Duration
+}
+
+type tokenService struct {
+	opts Options
+}
+
+// NewTokenService returns a TokenService signing tokens with HS256.
+func NewTokenService(opts Options) TokenService {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	return &tokenService{opts: opts}
+}
+
+func (s *tokenService) key() ([]byte, error) {
+	if s.opts.PrivateKey == nil {
+		return nil, ErrNoPrivateKey
+	}
+	key := s.opts.PrivateKey()
+	if key == "" {
+		return nil, ErrNoPrivateKey
+	}
+	return []byte(key), nil
+}
+
 // GenerateToken .
-func GenerateToken(user User) (*string, error) {
-	privKey := cfg.JwtPrivateKey()
+func (s *tokenService) GenerateToken(user User) (*string, error) {
+	privKey, err := s.key()
+	if err != nil {
+		return nil, err
+	}
 
+	now := time.Now()
 	claims := MyClaims{
 		StandardClaims: jwt.StandardClaims{
-			Issuer:    cfg.ApplicationName(),
+			Issuer:    s.opts.Issuer,
 			Subject:   "Auth",
-			Id:        fmt.Sprint(time.Now().UnixNano()),
-			IssuedAt:  time.Now().Unix(),
-			ExpiresAt: time.Now().Add(1 * 24 * time.Hour).Unix(),
+			Id:        fmt.Sprint(now.UnixNano()),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(s.opts.TTL).Unix(),
 		},
 		User: user,
 	}
@@ -50,7 +84,7 @@ func GenerateToken(user User) (*string, error) {
 		claims,
 	)
 
-	signedToken, err := token.SignedString([]byte(privKey))
+	signedToken, err := token.SignedString(privKey)
 	if err != nil {
 		return nil, err
 	}
@@ -59,18 +93,20 @@ func GenerateToken(user User) (*string, error) {
 }
 
 // GetDataFromToken .
-func GetDataFromToken(param string) (*User, error) {
+func (s *tokenService) GetDataFromToken(param string) (*User, error) {
 	token, err := jwt.ParseWithClaims(param, &MyClaims{}, func(x *jwt.Token) (interface{}, error) {
-		return []byte(cfg.JwtPrivateKey()), nil
+		if _, ok := x.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %s", x.Header["alg"])
+		}
+		return s.key()
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	claims, ok := token.Claims.(*MyClaims)
-	if !ok {
+	if !ok || !token.Valid {
 		return nil, fmt.Errorf("invalid token")
 	}
-
-	if !token.Valid {
-		return nil, err
-	}
 	return &claims.User, nil
 }
@@ -0,0 +1,91 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func key(k string) func() string {
+	return func() string { return k }
+}
+
+func TestTokenService(t *testing.T) {
+	t.Parallel()
+	user := User{ID: 1, Name: "rotan", Phone: "+628123456789"}
+
+	t.Run("verifies the tokens it generates", func(t *testing.T) {
+		t.Parallel()
+		tokens := NewTokenService(Options{PrivateKey: key("a"), Issuer: "users", TTL: time.Hour})
+		token, err := tokens.GenerateToken(user)
+		require.NoError(t, err)
+
+		got, err := tokens.GetDataFromToken(*token)
+		assert.NoError(t, err)
+		assert.Equal(t, &user, got)
+
+		claims := &MyClaims{}
+		_, _, err = new(jwt.Parser).ParseUnverified(*token, claims)
+		require.NoError(t, err)
+		assert.Equal(t, "users", claims.Issuer)
+		assert.Equal(t, claims.IssuedAt+int64(time.Hour/time.Second), claims.ExpiresAt)
+	})
+
+	t.Run("configurations coexist", func(t *testing.T) {
+		t.Parallel()
+		a := NewTokenService(Options{PrivateKey: key("a")})
+		b := NewTokenService(Options{PrivateKey: key("b")})
+		token, err := a.GenerateToken(user)
+		require.NoError(t, err)
+
+		_, err = b.GetDataFromToken(*token)
+		assert.Error(t, err)
+		_, err = a.GetDataFromToken(*token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("uses a rotated key at once", func(t *testing.T) {
+		t.Parallel()
+		current := "old"
+		tokens := NewTokenService(Options{PrivateKey: func() string { return current }})
+		token, err := tokens.GenerateToken(user)
+		require.NoError(t, err)
+
+		current = "new"
+		_, err = tokens.GetDataFromToken(*token)
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects expired tokens", func(t *testing.T) {
+		t.Parallel()
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, MyClaims{
+			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(-time.Minute).Unix()},
+			User:           user,
+		}).SignedString([]byte("a"))
+		require.NoError(t, err)
+
+		_, err = NewTokenService(Options{PrivateKey: key("a")}).GetDataFromToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects unsigned tokens", func(t *testing.T) {
+		t.Parallel()
+		token, err := jwt.NewWithClaims(jwt.SigningMethodNone, MyClaims{User: user}).
+			SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+
+		_, err = NewTokenService(Options{PrivateKey: key("a")}).GetDataFromToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("requires a private key", func(t *testing.T) {
+		t.Parallel()
+		for _, opts := range []Options{{}, {PrivateKey: key("")}} {
+			_, err := NewTokenService(opts).GenerateToken(user)
+			assert.ErrorIs(t, err, ErrNoPrivateKey)
+		}
+	})
+}
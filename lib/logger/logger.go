@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the value of every sensitive attribute.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are matched case-insensitively against any part of an attribute key.
+var sensitiveKeys = []string{"password", "token", "secret", "authorization", "private_key", "database_url", "dsn", "email"}
+
+// errorKeys are the keys of error attributes, whose values are redacted like
+// messages.
+var errorKeys = []string{"error", "err"}
+
+var (
+	// tokenPattern matches bearer credentials, JWTs and the opaque tokens of
+	// the service.
+	tokenPattern = regexp.MustCompile(`(?i)bearer\s+\S+|\beyJ[\w-]*\.[\w-]*\.[\w-]*|\b[\w-]{32,}`)
+	emailPattern = regexp.MustCompile(`[\w.%+-]+@[\w-]+(\.[\w-]+)+`)
+	phonePattern = regexp.MustCompile(`\+?\b\d{9,15}\b`)
+)
+
+type ctxKey struct{}
+
+// New creates a JSON logger that redacts passwords, tokens, emails and phone
+// numbers, by key and within messages and errors.
+func New(w io.Writer, level string) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       parseLevel(level),
+		ReplaceAttr: redact,
+	}))
+}
+
+// WithContext returns a copy of ctx carrying l.
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	if len(groups) == 0 && a.Key == slog.MessageKey {
+		return slog.String(a.Key, RedactText(a.Value.String()))
+	}
+	if err, ok := a.Value.Any().(error); ok && a.Value.Kind() == slog.KindAny {
+		return slog.String(a.Key, RedactText(err.Error()))
+	}
+	for _, k := range sensitiveKeys {
+		if strings.Contains(key, k) {
+			return slog.String(a.Key, Redacted)
+		}
+	}
+	if strings.Contains(key, "phone") {
+		return slog.String(a.Key, MaskPhone(a.Value.String()))
+	}
+	for _, k := range errorKeys {
+		if key == k {
+			return slog.String(a.Key, RedactText(a.Value.String()))
+		}
+	}
+	return a
+}
+
+// RedactText redacts the tokens and emails found in free text, e.g. an error
+// message, and masks its phone numbers.
+func RedactText(text string) string {
+	text = tokenPattern.ReplaceAllString(text, Redacted)
+	text = emailPattern.ReplaceAllString(text, Redacted)
+	return phonePattern.ReplaceAllStringFunc(text, MaskPhone)
+}
+
+// MaskPhone keeps the country code prefix and the last 4 digits of a phone number.
+func MaskPhone(phone string) string {
+	if len(phone) <= 7 {
+		return strings.Repeat("*", len(phone))
+	}
+	return phone[:3] + strings.Repeat("*", len(phone)-7) + phone[len(phone)-4:]
+}
+
+func parseLevel(level string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
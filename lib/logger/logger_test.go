@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_Redaction(t *testing.T) {
+	t.Parallel()
+
+	t.Run("redacts passwords, tokens and phone numbers", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := New(&buf, "info")
+
+		l.Info("login",
+			slog.String("password", "Password123!"),
+			slog.String("token", "eyJhbGciOiJIUzI1NiJ9"),
+			slog.Group("payload", slog.String("Authorization", "Bearer abc")),
+			slog.String("phone", "+628123456789"),
+			slog.Int64("user_id", 1),
+		)
+
+		var out map[string]any
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+		assert.Equal(t, Redacted, out["password"])
+		assert.Equal(t, Redacted, out["token"])
+		assert.Equal(t, Redacted, out["payload"].(map[string]any)["Authorization"])
+		assert.Equal(t, "+62******6789", out["phone"])
+		assert.Equal(t, float64(1), out["user_id"])
+	})
+
+	t.Run("redacts configuration secrets", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := New(&buf, "info")
+
+		l.Info("config",
+			slog.String("JWT_PRIVATE_KEY", "s3cret"),
+			slog.String("DATABASE_URL", "postgres://users:s3cret@db/users"),
+			slog.String("dsn", "postgres://users:s3cret@db/users"),
+		)
+
+		assert.NotContains(t, buf.String(), "s3cret")
+	})
+
+	t.Run("redacts emails", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := New(&buf, "info")
+
+		l.Info("login", slog.String("email", "rotan@example.com"))
+
+		assert.NotContains(t, buf.String(), "rotan@example.com")
+	})
+
+	t.Run("redacts personal data within messages and errors", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := New(&buf, "info")
+
+		l.Error("failed to mail rotan@example.com at 08123456789",
+			slog.String("error", "send to +628123456789: rejected token eyJhbGciOiJIUzI1NiJ9.eyJpZCI6MX0.sig"),
+			slog.Any("cause", errors.New("bad reset token 3q2-7wAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")),
+			slog.String("request_id", "5f1c3e7a-9b2d-4c8e-8f6a-1d2e3f4a5b6c"),
+		)
+
+		var out map[string]any
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+		assert.Equal(t, "failed to mail [REDACTED] at 081****6789", out["msg"])
+		assert.Equal(t, "send to +62******6789: rejected token [REDACTED]", out["error"])
+		assert.Equal(t, "bad reset token [REDACTED]", out["cause"])
+		assert.Equal(t, "5f1c3e7a-9b2d-4c8e-8f6a-1d2e3f4a5b6c", out["request_id"])
+	})
+
+	t.Run("carries logger in context", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := New(&buf, "info").With(slog.String("request_id", "abc"))
+		ctx := WithContext(context.Background(), l)
+
+		FromContext(ctx).Info("hello")
+
+		assert.Contains(t, buf.String(), `"request_id":"abc"`)
+	})
+
+	t.Run("respects level", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := New(&buf, "warn")
+
+		l.Info("hidden")
+
+		assert.Empty(t, buf.String())
+	})
+}
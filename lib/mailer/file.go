@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type fileMailer struct {
+	dir string
+}
+
+// NewFileMailer returns a Mailer that writes every message to its own file in
+// dir instead of delivering it, for local development and tests.
+func NewFileMailer(dir string) (Mailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &fileMailer{
+		dir: dir,
+	}, nil
+}
+
+func (m *fileMailer) Send(_ context.Context, message Message) error {
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	content := fmt.Sprintf("To: %s\r\nSubject: %s\r\n\r\n%s\r\n", message.To, message.Subject, message.Body)
+	return os.WriteFile(filepath.Join(m.dir, name), []byte(content), 0o600)
+}
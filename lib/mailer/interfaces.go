@@ -0,0 +1,15 @@
+// This file contains the interface every mail delivery backend implements.
+package mailer
+
+import "context"
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+//go:generate mockgen -source interfaces.go -destination interfaces.mock.gen.go -package=mailer
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
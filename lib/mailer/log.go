@@ -0,0 +1,25 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/SawitProRecruitment/UserService/lib/logger"
+)
+
+type logMailer struct{}
+
+// NewLogMailer returns a Mailer that writes messages to the request logger
+// instead of delivering them, for local development.
+func NewLogMailer() Mailer {
+	return &logMailer{}
+}
+
+func (m *logMailer) Send(ctx context.Context, message Message) error {
+	logger.FromContext(ctx).Info("mail sent",
+		slog.String("to", message.To),
+		slog.String("subject", message.Subject),
+		slog.String("body", message.Body),
+	)
+	return nil
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/SawitProRecruitment/UserService/lib/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// Init installs the global meter provider, which periodically exports every
+// instrument of Meter.
+func Init(ctx context.Context, opts telemetry.Options) (telemetry.ShutdownFunc, error) {
+	if !opts.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := telemetry.NewExporter(opts, "metrics", telemetry.Exporters[sdkmetric.Exporter]{
+		Otlp: func(url string) (sdkmetric.Exporter, error) {
+			var exporterOpts []otlpmetrichttp.Option
+			if url != "" {
+				exporterOpts = append(exporterOpts, otlpmetrichttp.WithEndpointURL(url))
+			}
+			return otlpmetrichttp.New(ctx, exporterOpts...)
+		},
+		Stdout: func() (sdkmetric.Exporter, error) {
+			return stdoutmetric.New(stdoutmetric.WithPrettyPrint())
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := opts.Resource()
+	if err != nil {
+		return nil, err
+	}
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		sdkmetric.WithResource(res),
+	)
+	otel.SetMeterProvider(mp)
+
+	return mp.Shutdown, nil
+}
+
+// Meter returns the meter shared by every layer of the service. Instruments
+// may be created before Init, they record once it is called.
+func Meter() metric.Meter {
+	return otel.Meter(telemetry.InstrumentationName)
+}
@@ -0,0 +1,57 @@
+package password
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptParams .
+type BcryptParams struct {
+	Cost int
+}
+
+// DefaultBcryptParams .
+var DefaultBcryptParams = BcryptParams{
+	Cost: bcrypt.DefaultCost,
+}
+
+type bcryptHasher struct {
+	params BcryptParams
+}
+
+// NewBcrypt returns a PasswordHasher producing "$2a$<cost>$..." hashes. Zero
+// or out of range costs use DefaultBcryptParams. Passwords longer than 72
+// bytes are rejected with ErrTooLong.
+func NewBcrypt(params BcryptParams) PasswordHasher {
+	if params.Cost < bcrypt.MinCost || params.Cost > bcrypt.MaxCost {
+		params.Cost = DefaultBcryptParams.Cost
+	}
+	return &bcryptHasher{
+		params: params,
+	}
+}
+
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.params.Cost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", ErrTooLong
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (h *bcryptHasher) Compare(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatch
+	}
+	return err
+}
+
+func (h *bcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.params.Cost
+}
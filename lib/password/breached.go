@@ -0,0 +1,82 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// breached.txt holds the SHA-1 of common passwords found in data breaches.
+//
+//go:embed breached.txt
+var bundledBreachedList string
+
+// BreachedList is a k-anonymity index of breached password hashes: SHA-1
+// hashes are grouped by their first 5 hex characters like the Pwned Passwords
+// range API, so only the suffixes sharing a prefix are ever compared.
+type BreachedList struct {
+	suffixes map[string]map[string]struct{}
+}
+
+// ParseBreachedList reads upper or lower case SHA-1 hex hashes, one per line,
+// optionally followed by ":<count>" as in the Pwned Passwords downloads. Empty
+// lines and lines starting with # are ignored.
+func ParseBreachedList(r io.Reader) (*BreachedList, error) {
+	list := &BreachedList{
+		suffixes: map[string]map[string]struct{}{},
+	}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(text, ":")
+		hash = strings.ToUpper(hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 2*sha1.Size {
+			return nil, fmt.Errorf("password: invalid SHA-1 hash on line %d of breached password list", line)
+		}
+		prefix, suffix := hash[:5], hash[5:]
+		if list.suffixes[prefix] == nil {
+			list.suffixes[prefix] = map[string]struct{}{}
+		}
+		list.suffixes[prefix][suffix] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// LoadBreachedList reads a list in the ParseBreachedList format from path.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseBreachedList(f)
+}
+
+// BundledBreachedList returns the list embedded in the binary.
+var BundledBreachedList = sync.OnceValue(func() *BreachedList {
+	list, err := ParseBreachedList(strings.NewReader(bundledBreachedList))
+	if err != nil {
+		panic(err)
+	}
+	return list
+})
+
+// Contains reports whether password is in the list.
+func (l *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, ok := l.suffixes[hash[:5]][hash[5:]]
+	return ok
+}
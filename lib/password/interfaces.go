@@ -0,0 +1,14 @@
+// This file contains the interface every password hashing algorithm implements.
+package password
+
+//go:generate mockgen -source interfaces.go -destination interfaces.mock.gen.go -package=password
+type PasswordHasher interface {
+	// Hash returns the encoded hash of password, including the algorithm and
+	// its parameters.
+	Hash(password string) (string, error)
+	// Compare returns ErrMismatch when password does not match hash.
+	Compare(hash, password string) error
+	// NeedsRehash reports whether hash was made with another algorithm or
+	// other parameters than the ones currently configured.
+	NeedsRehash(hash string) bool
+}
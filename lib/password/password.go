@@ -0,0 +1,111 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrMismatch is returned when a password does not match its hash.
+	ErrMismatch = fmt.Errorf("password: hash and password mismatch")
+	// ErrTooLong is returned by algorithms with an input limit instead of
+	// silently truncating the password.
+	ErrTooLong = fmt.Errorf("password: password too long")
+	// ErrUnknownHash is returned for hashes made by an unsupported algorithm.
+	ErrUnknownHash = fmt.Errorf("password: unknown hash format")
+)
+
+type Algorithm string
+
+const (
+	AlgorithmArgon2id Algorithm = "argon2id"
+	AlgorithmBcrypt   Algorithm = "bcrypt"
+)
+
+// ParseAlgorithm .
+func ParseAlgorithm(s string) (Algorithm, error) {
+	switch a := Algorithm(strings.ToLower(strings.TrimSpace(s))); a {
+	case AlgorithmArgon2id, AlgorithmBcrypt:
+		return a, nil
+	default:
+		return "", fmt.Errorf("password: unknown algorithm %q", s)
+	}
+}
+
+// Options .
+type Options struct {
+	// Algorithm new hashes are made with.
+	Algorithm Algorithm
+	Argon2id  Argon2idParams
+	Bcrypt    BcryptParams
+}
+
+// DefaultOptions hashes with Argon2id using the OWASP recommended parameters.
+var DefaultOptions = Options{
+	Algorithm: AlgorithmArgon2id,
+	Argon2id:  DefaultArgon2idParams,
+	Bcrypt:    DefaultBcryptParams,
+}
+
+type hasher struct {
+	algorithm Algorithm
+	argon2id  PasswordHasher
+	bcrypt    PasswordHasher
+}
+
+// NewHasher returns a PasswordHasher that hashes with opts.Algorithm and
+// verifies hashes of every supported algorithm, so existing hashes keep
+// working after the algorithm or its parameters change.
+func NewHasher(opts Options) PasswordHasher {
+	if opts.Algorithm == "" {
+		opts.Algorithm = DefaultOptions.Algorithm
+	}
+	return &hasher{
+		algorithm: opts.Algorithm,
+		argon2id:  NewArgon2id(opts.Argon2id),
+		bcrypt:    NewBcrypt(opts.Bcrypt),
+	}
+}
+
+func (h *hasher) Hash(password string) (string, error) {
+	if h.algorithm == AlgorithmBcrypt {
+		return h.bcrypt.Hash(password)
+	}
+	return h.argon2id.Hash(password)
+}
+
+func (h *hasher) Compare(hash, password string) error {
+	algorithm, ok := identify(hash)
+	if !ok {
+		return ErrUnknownHash
+	}
+	return h.of(algorithm).Compare(hash, password)
+}
+
+func (h *hasher) NeedsRehash(hash string) bool {
+	algorithm, ok := identify(hash)
+	if !ok || algorithm != h.algorithm {
+		return true
+	}
+	return h.of(algorithm).NeedsRehash(hash)
+}
+
+func (h *hasher) of(algorithm Algorithm) PasswordHasher {
+	if algorithm == AlgorithmBcrypt {
+		return h.bcrypt
+	}
+	return h.argon2id
+}
+
+// identify returns the algorithm from the identifier of a PHC or modular
+// crypt formatted hash, e.g. "$argon2id$..." or "$2a$...".
+func identify(hash string) (Algorithm, bool) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return AlgorithmArgon2id, true
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return AlgorithmBcrypt, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,180 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestArgon2id(t *testing.T) {
+	t.Parallel()
+	h := NewArgon2id(DefaultArgon2idParams)
+
+	t.Run("hash is PHC formatted", func(t *testing.T) {
+		hash, err := h.Hash("Password123!")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$"), hash)
+		assert.Len(t, strings.Split(hash, "$"), 6)
+	})
+
+	t.Run("compare", func(t *testing.T) {
+		hash, _ := h.Hash("Password123!")
+		assert.NoError(t, h.Compare(hash, "Password123!"))
+		assert.ErrorIs(t, h.Compare(hash, "Password123?"), ErrMismatch)
+	})
+
+	t.Run("passwords longer than 72 bytes are not truncated", func(t *testing.T) {
+		password := strings.Repeat("ü", 40) + "A1!"
+		hash, err := h.Hash(password)
+		assert.NoError(t, err)
+		assert.ErrorIs(t, h.Compare(hash, strings.Repeat("ü", 40)+"B2?"), ErrMismatch)
+	})
+
+	t.Run("needs rehash when parameters change", func(t *testing.T) {
+		hash, _ := h.Hash("Password123!")
+		assert.False(t, h.NeedsRehash(hash))
+
+		stronger := NewArgon2id(Argon2idParams{Memory: 32 * 1024})
+		assert.True(t, stronger.NeedsRehash(hash))
+	})
+
+	t.Run("invalid hash", func(t *testing.T) {
+		assert.ErrorIs(t, h.Compare("$argon2id$v=19$m=1", "Password123!"), ErrUnknownHash)
+		assert.Error(t, h.Compare("$argon2id$v=19$m=x,t=2,p=1$c2FsdA$a2V5", "Password123!"))
+	})
+}
+
+func TestBcrypt(t *testing.T) {
+	t.Parallel()
+	h := NewBcrypt(BcryptParams{Cost: bcrypt.MinCost})
+
+	t.Run("compare", func(t *testing.T) {
+		hash, err := h.Hash("Password123!")
+		assert.NoError(t, err)
+		assert.NoError(t, h.Compare(hash, "Password123!"))
+		assert.ErrorIs(t, h.Compare(hash, "Password123?"), ErrMismatch)
+	})
+
+	t.Run("rejects passwords longer than 72 bytes", func(t *testing.T) {
+		_, err := h.Hash(strings.Repeat("ü", 37))
+		assert.ErrorIs(t, err, ErrTooLong)
+	})
+
+	t.Run("needs rehash when cost changes", func(t *testing.T) {
+		hash, _ := h.Hash("Password123!")
+		assert.False(t, h.NeedsRehash(hash))
+		assert.True(t, NewBcrypt(BcryptParams{Cost: bcrypt.MinCost + 1}).NeedsRehash(hash))
+	})
+}
+
+func TestHasher(t *testing.T) {
+	t.Parallel()
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("Password123!"), bcrypt.MinCost)
+
+	t.Run("verifies hashes of every algorithm", func(t *testing.T) {
+		h := NewHasher(DefaultOptions)
+		assert.NoError(t, h.Compare(string(legacy), "Password123!"))
+
+		hash, err := h.Hash("Password123!")
+		assert.NoError(t, err)
+		assert.NoError(t, h.Compare(hash, "Password123!"))
+		assert.ErrorIs(t, h.Compare(hash, "Password123?"), ErrMismatch)
+	})
+
+	t.Run("needs rehash with another algorithm", func(t *testing.T) {
+		h := NewHasher(DefaultOptions)
+		assert.True(t, h.NeedsRehash(string(legacy)))
+
+		hash, _ := h.Hash("Password123!")
+		assert.False(t, h.NeedsRehash(hash))
+	})
+
+	t.Run("hashes with the configured algorithm", func(t *testing.T) {
+		h := NewHasher(Options{Algorithm: AlgorithmBcrypt, Bcrypt: BcryptParams{Cost: bcrypt.MinCost}})
+		hash, err := h.Hash("Password123!")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(hash, "$2a$"))
+		assert.False(t, h.NeedsRehash(hash))
+	})
+
+	t.Run("unknown hash", func(t *testing.T) {
+		h := NewHasher(DefaultOptions)
+		assert.ErrorIs(t, h.Compare("plaintext", "plaintext"), ErrUnknownHash)
+		assert.True(t, h.NeedsRehash("plaintext"))
+	})
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	a, err := ParseAlgorithm(" Argon2id ")
+	assert.NoError(t, err)
+	assert.Equal(t, AlgorithmArgon2id, a)
+
+	_, err = ParseAlgorithm("md5")
+	assert.Error(t, err)
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+	policy := NewPolicy(DefaultPolicyOptions)
+	info := PersonalInfo{Name: "Rotan Tantra", Phone: "+628123456789", Email: "rt@example.com"}
+
+	tests := []struct {
+		name     string
+		password string
+		rule     Rule
+	}{
+		{name: "too short", password: "Ab1!", rule: RuleMinLength},
+		{name: "too long", password: strings.Repeat("Ab1!", 17), rule: RuleMaxLength},
+		{name: "missing uppercase", password: "sawit-pro-1", rule: RuleUppercase},
+		{name: "missing digit", password: "Sawit-Pro", rule: RuleDigit},
+		{name: "missing special", password: "SawitPro1", rule: RuleSpecial},
+		{name: "contains name", password: "Sawit-ROTAN-1", rule: RulePersonalInfo},
+		{name: "contains phone", password: "Sawit-234567", rule: RulePersonalInfo},
+		{name: "breached", password: "P@ssw0rd1", rule: RuleBreached},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var policyErr *PolicyError
+			assert.ErrorAs(t, policy.Check(tt.password, info), &policyErr)
+			assert.Equal(t, tt.rule, policyErr.Rule)
+		})
+	}
+
+	t.Run("counts characters, not bytes", func(t *testing.T) {
+		assert.NoError(t, policy.Check("Sawit-Pröööö-1"+strings.Repeat("ü", 40), info))
+	})
+
+	t.Run("short name words and email local parts are allowed", func(t *testing.T) {
+		assert.NoError(t, policy.Check("Sawit-rt-Pro-1", PersonalInfo{Name: "Ed", Email: "rt@example.com"}))
+	})
+
+	t.Run("rules can be disabled", func(t *testing.T) {
+		lax := NewPolicy(PolicyOptions{MinLength: 4})
+		assert.NoError(t, lax.Check("rotan", info))
+	})
+}
+
+func TestBreachedList(t *testing.T) {
+	t.Parallel()
+
+	t.Run("parses pwned passwords format", func(t *testing.T) {
+		list, err := ParseBreachedList(strings.NewReader("# comment\n\n5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8:3861493\n"))
+		assert.NoError(t, err)
+		assert.True(t, list.Contains("password"))
+		assert.False(t, list.Contains("Password"))
+	})
+
+	t.Run("rejects invalid hashes", func(t *testing.T) {
+		_, err := ParseBreachedList(strings.NewReader("password\n"))
+		assert.Error(t, err)
+	})
+
+	t.Run("bundled list", func(t *testing.T) {
+		assert.True(t, BundledBreachedList().Contains("123456"))
+		assert.False(t, BundledBreachedList().Contains("Sawit-Pro-Kebun-7"))
+	})
+}
@@ -0,0 +1,166 @@
+package password
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rule identifies the policy rule a password violates.
+type Rule string
+
+const (
+	RuleMinLength    Rule = "min_length"
+	RuleMaxLength    Rule = "max_length"
+	RuleUppercase    Rule = "uppercase"
+	RuleLowercase    Rule = "lowercase"
+	RuleDigit        Rule = "digit"
+	RuleSpecial      Rule = "special"
+	RulePersonalInfo Rule = "personal_info"
+	RuleBreached     Rule = "breached"
+)
+
+// PolicyError is returned by Policy.Check for the first rule a password
+// violates. Param is the rule's limit, e.g. the minimum length.
+type PolicyError struct {
+	Rule  Rule
+	Param string
+}
+
+func (err *PolicyError) Error() string {
+	if err.Param != "" {
+		return fmt.Sprintf("password: violates %s policy (%s)", err.Rule, err.Param)
+	}
+	return fmt.Sprintf("password: violates %s policy", err.Rule)
+}
+
+// PolicyOptions .
+type PolicyOptions struct {
+	// MinLength and MaxLength count characters, not bytes.
+	MinLength        int
+	MaxLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSpecial   bool
+	// DisallowPersonalInfo rejects passwords containing a word of the name,
+	// part of the phone number or the local part of the email of the user.
+	DisallowPersonalInfo bool
+	// Breached rejects passwords known from data breaches, nil disables it.
+	Breached *BreachedList
+}
+
+// DefaultPolicyOptions keeps the rules the service always had, 6 to 64
+// characters with an uppercase letter, a digit and a special character, and
+// adds the personal info and bundled breached password checks.
+var DefaultPolicyOptions = PolicyOptions{
+	MinLength:            6,
+	MaxLength:            64,
+	RequireUppercase:     true,
+	RequireDigit:         true,
+	RequireSpecial:       true,
+	DisallowPersonalInfo: true,
+	Breached:             BundledBreachedList(),
+}
+
+// Policy decides which passwords can be set. It is not meant for login, so
+// tightening it never locks out existing users.
+type Policy struct {
+	opts PolicyOptions
+}
+
+// NewPolicy .
+func NewPolicy(opts PolicyOptions) *Policy {
+	return &Policy{
+		opts: opts,
+	}
+}
+
+// personalInfoMinLength is the shortest name word or email local part that
+// cannot appear in a password; shorter ones match too many passwords.
+const personalInfoMinLength = 3
+
+// phoneInfoMinLength is the number of consecutive digits of the phone number
+// that cannot appear in a password.
+const phoneInfoMinLength = 6
+
+// PersonalInfo about the user the password is set for.
+type PersonalInfo struct {
+	Name  string
+	Phone string
+	Email string
+}
+
+// Check returns a *PolicyError for the first rule password violates.
+func (p *Policy) Check(password string, info PersonalInfo) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.opts.MinLength {
+		return &PolicyError{Rule: RuleMinLength, Param: strconv.Itoa(p.opts.MinLength)}
+	}
+	if p.opts.MaxLength > 0 && length > p.opts.MaxLength {
+		return &PolicyError{Rule: RuleMaxLength, Param: strconv.Itoa(p.opts.MaxLength)}
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r):
+			hasSpecial = true
+		}
+	}
+	switch {
+	case p.opts.RequireUppercase && !hasUpper:
+		return &PolicyError{Rule: RuleUppercase}
+	case p.opts.RequireLowercase && !hasLower:
+		return &PolicyError{Rule: RuleLowercase}
+	case p.opts.RequireDigit && !hasDigit:
+		return &PolicyError{Rule: RuleDigit}
+	case p.opts.RequireSpecial && !hasSpecial:
+		return &PolicyError{Rule: RuleSpecial}
+	}
+
+	if p.opts.DisallowPersonalInfo && containsPersonalInfo(password, info) {
+		return &PolicyError{Rule: RulePersonalInfo}
+	}
+	if p.opts.Breached != nil && p.opts.Breached.Contains(password) {
+		return &PolicyError{Rule: RuleBreached}
+	}
+	return nil
+}
+
+func containsPersonalInfo(password string, info PersonalInfo) bool {
+	password = strings.ToLower(password)
+
+	words := strings.FieldsFunc(info.Name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if local, _, ok := strings.Cut(info.Email, "@"); ok {
+		words = append(words, local)
+	}
+	for _, word := range words {
+		if utf8.RuneCountInString(word) >= personalInfoMinLength && strings.Contains(password, strings.ToLower(word)) {
+			return true
+		}
+	}
+
+	digits := strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, info.Phone)
+	for i := 0; i+phoneInfoMinLength <= len(digits); i++ {
+		if strings.Contains(password, digits[i:i+phoneInfoMinLength]) {
+			return true
+		}
+	}
+	return false
+}
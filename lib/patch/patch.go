@@ -0,0 +1,61 @@
+// Package patch implements the members of a JSON Merge Patch (RFC 7396)
+// document: a member that is absent leaves the target unchanged, null removes
+// it and any other value replaces it.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// MIMEApplicationMergePatchJSON is the RFC 7396 media type.
+const MIMEApplicationMergePatchJSON = "application/merge-patch+json"
+
+// Field is a member of a merge patch document.
+type Field[T any] struct {
+	// Set is true when the member is present, even if it is null.
+	Set bool
+	// Null is true when the member is present and null.
+	Null  bool
+	Value T
+}
+
+// Value returns a Field replacing the target with v.
+func Value[T any](v T) Field[T] {
+	return Field[T]{Set: true, Value: v}
+}
+
+// Null returns a Field removing the target.
+func Null[T any]() Field[T] {
+	return Field[T]{Set: true, Null: true}
+}
+
+// UnmarshalJSON is only called for members present in the document.
+func (f *Field[T]) UnmarshalJSON(b []byte) error {
+	f.Set = true
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		f.Null = true
+		return nil
+	}
+	return json.Unmarshal(b, &f.Value)
+}
+
+// MarshalJSON encodes null for removed members. Members that are not set
+// have no encoding, they have to be left out of the document, e.g. with the
+// omitzero option.
+func (f Field[T]) MarshalJSON() ([]byte, error) {
+	if !f.Set || f.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(f.Value)
+}
+
+// IsZero reports whether the member is absent, for the omitzero option.
+func (f Field[T]) IsZero() bool {
+	return !f.Set
+}
+
+// Get returns the value and whether the member replaces the target.
+func (f Field[T]) Get() (T, bool) {
+	return f.Value, f.Set && !f.Null
+}
@@ -0,0 +1,50 @@
+package patch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestField(t *testing.T) {
+	t.Parallel()
+
+	var doc struct {
+		Name   Field[string] `json:"name"`
+		Email  Field[string] `json:"email"`
+		Locale Field[string] `json:"locale"`
+	}
+	err := json.Unmarshal([]byte(`{"name": "rotan", "email": null}`), &doc)
+	assert.NoError(t, err)
+
+	t.Run("value replaces", func(t *testing.T) {
+		assert.Equal(t, Value("rotan"), doc.Name)
+		v, ok := doc.Name.Get()
+		assert.True(t, ok)
+		assert.Equal(t, "rotan", v)
+	})
+
+	t.Run("null removes", func(t *testing.T) {
+		assert.Equal(t, Null[string](), doc.Email)
+		_, ok := doc.Email.Get()
+		assert.False(t, ok)
+	})
+
+	t.Run("absent leaves unchanged", func(t *testing.T) {
+		assert.False(t, doc.Locale.Set)
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		var f Field[string]
+		assert.Error(t, json.Unmarshal([]byte(`1`), &f))
+	})
+
+	t.Run("marshal", func(t *testing.T) {
+		b, _ := json.Marshal(struct {
+			Name  Field[string] `json:"name"`
+			Email Field[string] `json:"email"`
+		}{Name: Value("rotan"), Email: Null[string]()})
+		assert.JSONEq(t, `{"name": "rotan", "email": null}`, string(b))
+	})
+}
@@ -0,0 +1,138 @@
+package phone
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPhone is returned for numbers that cannot be normalized.
+var ErrInvalidPhone = fmt.Errorf("phone: invalid phone number")
+
+// Rule limits the length of the national significant number, i.e. the digits
+// after the country calling code.
+type Rule struct {
+	MinLength int
+	MaxLength int
+}
+
+// KnownRules are used for allowed country codes configured without a length rule.
+var KnownRules = map[string]Rule{
+	"1":  {MinLength: 10, MaxLength: 10},
+	"44": {MinLength: 10, MaxLength: 10},
+	"60": {MinLength: 9, MaxLength: 10},
+	"61": {MinLength: 9, MaxLength: 9},
+	"62": {MinLength: 10, MaxLength: 13},
+	"63": {MinLength: 10, MaxLength: 10},
+	"65": {MinLength: 8, MaxLength: 8},
+	"66": {MinLength: 9, MaxLength: 9},
+	"84": {MinLength: 9, MaxLength: 10},
+	"91": {MinLength: 10, MaxLength: 10},
+}
+
+// Options .
+type Options struct {
+	// DefaultCountryCode is assumed for national numbers like 0812...
+	DefaultCountryCode string
+	// Countries is the allow-list of country calling codes.
+	Countries map[string]Rule
+}
+
+// DefaultOptions only accepts Indonesian numbers.
+var DefaultOptions = Options{
+	DefaultCountryCode: "62",
+	Countries:          map[string]Rule{"62": KnownRules["62"]},
+}
+
+// Parser normalizes phone numbers to E.164.
+type Parser struct {
+	opts Options
+}
+
+// NewParser .
+func NewParser(opts Options) *Parser {
+	return &Parser{
+		opts: opts,
+	}
+}
+
+// Normalize returns the canonical E.164 form of raw, e.g. "+6281234567890"
+// for "0812-3456-7890", "62 812 3456 7890" or "+62 (812) 3456 7890".
+func (p *Parser) Normalize(raw string) (string, error) {
+	number := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "").Replace(strings.TrimSpace(raw))
+
+	var digits string
+	switch {
+	case strings.HasPrefix(number, "+"):
+		digits = number[1:]
+	case strings.HasPrefix(number, "00"):
+		digits = number[2:]
+	case strings.HasPrefix(number, "0"):
+		digits = p.opts.DefaultCountryCode + number[1:]
+	default:
+		digits = number
+	}
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return "", ErrInvalidPhone
+	}
+
+	// Country calling codes are prefix free, so the first match is the only one.
+	for i := 1; i <= 3 && i < len(digits); i++ {
+		countryCode := digits[:i]
+		rule, ok := p.opts.Countries[countryCode]
+		if !ok {
+			continue
+		}
+		// The trunk prefix is often kept after the country code, e.g. +62 0812...
+		nsn := strings.TrimPrefix(digits[i:], "0")
+		if len(nsn) < rule.MinLength || len(nsn) > rule.MaxLength {
+			return "", ErrInvalidPhone
+		}
+		return "+" + countryCode + nsn, nil
+	}
+	return "", ErrInvalidPhone
+}
+
+// Valid reports whether raw can be normalized.
+func (p *Parser) Valid(raw string) bool {
+	_, err := p.Normalize(raw)
+	return err == nil
+}
+
+// ParseCountryRules parses an allow-list like "62:10-13,65:8,60". Codes without
+// an explicit rule use KnownRules.
+func ParseCountryRules(s string) (map[string]Rule, error) {
+	rules := map[string]Rule{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(entry), "+"))
+		if entry == "" {
+			continue
+		}
+		code, lengths, hasRule := strings.Cut(entry, ":")
+		if _, err := strconv.Atoi(code); err != nil || len(code) > 3 {
+			return nil, fmt.Errorf("phone: invalid country code %q", code)
+		}
+		if !hasRule {
+			rule, ok := KnownRules[code]
+			if !ok {
+				return nil, fmt.Errorf("phone: no length rule known for country code %q", code)
+			}
+			rules[code] = rule
+			continue
+		}
+		minLength, maxLength, isRange := strings.Cut(lengths, "-")
+		if !isRange {
+			maxLength = minLength
+		}
+		minLen, errMin := strconv.Atoi(minLength)
+		maxLen, errMax := strconv.Atoi(maxLength)
+		if errMin != nil || errMax != nil || minLen <= 0 || minLen > maxLen {
+			return nil, fmt.Errorf("phone: invalid length rule %q for country code %q", lengths, code)
+		}
+		rules[code] = Rule{MinLength: minLen, MaxLength: maxLen}
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("phone: no country code allowed")
+	}
+	return rules, nil
+}
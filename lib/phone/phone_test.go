@@ -0,0 +1,63 @@
+package phone
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParser_Normalize(t *testing.T) {
+	t.Parallel()
+
+	rules, err := ParseCountryRules("62,65,60:9-10")
+	assert.NoError(t, err)
+	p := NewParser(Options{DefaultCountryCode: "62", Countries: rules})
+
+	valid := map[string]string{
+		"+6281234567890":       "+6281234567890",
+		"+62 812-3456-7890":    "+6281234567890",
+		"081234567890":         "+6281234567890",
+		"6281234567890":        "+6281234567890",
+		"006281234567890":      "+6281234567890",
+		"+62 (0812) 3456 7890": "+6281234567890",
+		"+65 6123 4567":        "+6561234567",
+		"+60 12-345 6789":      "+60123456789",
+	}
+	for raw, expected := range valid {
+		actual, err := p.Normalize(raw)
+		assert.NoError(t, err, raw)
+		assert.Equal(t, expected, actual, raw)
+	}
+
+	invalid := []string{
+		"",
+		"+",
+		"+28123456789",
+		"+62123",
+		"+6212345678901234",
+		"+65 6123 45678",
+		"+62 812 3456 abcd",
+		"+1 202 555 0100",
+	}
+	for _, raw := range invalid {
+		_, err := p.Normalize(raw)
+		assert.ErrorIs(t, err, ErrInvalidPhone, raw)
+	}
+}
+
+func TestParseCountryRules(t *testing.T) {
+	t.Parallel()
+
+	rules, err := ParseCountryRules("+62:10-13, 65:8, 1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Rule{
+		"62": {MinLength: 10, MaxLength: 13},
+		"65": {MinLength: 8, MaxLength: 8},
+		"1":  {MinLength: 10, MaxLength: 10},
+	}, rules)
+
+	for _, invalid := range []string{"", "abc", "999", "62:13-10", "62:x", "1234"} {
+		_, err := ParseCountryRules(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
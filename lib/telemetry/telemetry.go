@@ -0,0 +1,83 @@
+// Package telemetry has what the OpenTelemetry signals of the service, traces
+// and metrics, share: how they are exported and the resource they describe.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+const (
+	// ExporterOtlp pushes to an OTLP/HTTP collector.
+	ExporterOtlp = "otlp"
+	// ExporterStdout pretty prints to stdout, useful for local debugging.
+	ExporterStdout = "stdout"
+	// ExporterNone exports nothing.
+	ExporterNone = "none"
+)
+
+// InstrumentationName names the tracer and the meter of the service.
+const InstrumentationName = "github.com/SawitProRecruitment/UserService"
+
+// ShutdownFunc exports what is pending and releases the exporter.
+type ShutdownFunc func(ctx context.Context) error
+
+// Options .
+type Options struct {
+	ServiceName  string
+	Exporter     string
+	OtlpEndpoint string
+}
+
+// Enabled reports whether anything is exported.
+func (opts Options) Enabled() bool {
+	return opts.Exporter != ExporterNone && opts.Exporter != ""
+}
+
+// Validate reports an exporter NewExporter does not know.
+func (opts Options) Validate() error {
+	switch opts.Exporter {
+	case "", ExporterNone, ExporterOtlp, ExporterStdout:
+		return nil
+	default:
+		return fmt.Errorf("unknown exporter %q, want %s, %s or %s", opts.Exporter, ExporterOtlp, ExporterStdout, ExporterNone)
+	}
+}
+
+// Resource describes the service to the backends.
+func (opts Options) Resource() (*resource.Resource, error) {
+	return resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+	))
+}
+
+// Exporters build the exporter of a signal.
+type Exporters[E any] struct {
+	// Otlp is given the URL of the signal on the collector, empty to leave it
+	// to the OTEL_EXPORTER_OTLP_* variables.
+	Otlp   func(url string) (E, error)
+	Stdout func() (E, error)
+}
+
+// NewExporter returns the exporter of opts for signal, e.g. "traces".
+func NewExporter[E any](opts Options, signal string, exporters Exporters[E]) (E, error) {
+	switch opts.Exporter {
+	case ExporterOtlp:
+		var url string
+		if opts.OtlpEndpoint != "" {
+			// Like OTEL_EXPORTER_OTLP_ENDPOINT, the endpoint is the collector base URL.
+			url = strings.TrimRight(opts.OtlpEndpoint, "/") + "/v1/" + signal
+		}
+		return exporters.Otlp(url)
+	case ExporterStdout:
+		return exporters.Stdout()
+	default:
+		var none E
+		return none, fmt.Errorf("telemetry: unknown %s exporter %q", signal, opts.Exporter)
+	}
+}
@@ -0,0 +1,51 @@
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExporter(t *testing.T) {
+	t.Parallel()
+	exporters := Exporters[string]{
+		Otlp:   func(url string) (string, error) { return url, nil },
+		Stdout: func() (string, error) { return ExporterStdout, nil },
+	}
+
+	t.Run("otlp exports to the URL of the signal", func(t *testing.T) {
+		t.Parallel()
+		for _, endpoint := range []string{"http://collector:4318", "http://collector:4318/"} {
+			got, err := NewExporter(Options{Exporter: ExporterOtlp, OtlpEndpoint: endpoint}, "traces", exporters)
+			assert.NoError(t, err)
+			assert.Equal(t, "http://collector:4318/v1/traces", got)
+		}
+	})
+
+	t.Run("otlp without an endpoint leaves the URL to the environment", func(t *testing.T) {
+		t.Parallel()
+		got, err := NewExporter(Options{Exporter: ExporterOtlp}, "metrics", exporters)
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("stdout", func(t *testing.T) {
+		t.Parallel()
+		got, err := NewExporter(Options{Exporter: ExporterStdout}, "metrics", exporters)
+		assert.NoError(t, err)
+		assert.Equal(t, ExporterStdout, got)
+	})
+
+	t.Run("unknown exporter", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewExporter(Options{Exporter: "zipkin"}, "traces", exporters)
+		assert.EqualError(t, err, `telemetry: unknown traces exporter "zipkin"`)
+	})
+}
+
+func TestOptions_Enabled(t *testing.T) {
+	t.Parallel()
+	assert.False(t, Options{}.Enabled())
+	assert.False(t, Options{Exporter: ExporterNone}.Enabled())
+	assert.True(t, Options{Exporter: ExporterOtlp}.Enabled())
+}
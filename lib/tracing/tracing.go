@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/SawitProRecruitment/UserService/lib/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Init installs the global tracer provider and W3C propagators. Propagation
+// keeps working when no span is exported.
+func Init(ctx context.Context, opts telemetry.Options) (telemetry.ShutdownFunc, error) {
+	otel.SetTextMapPropagator(NewPropagator())
+
+	if !opts.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := telemetry.NewExporter(opts, "traces", telemetry.Exporters[sdktrace.SpanExporter]{
+		Otlp: func(url string) (sdktrace.SpanExporter, error) {
+			var exporterOpts []otlptracehttp.Option
+			if url != "" {
+				exporterOpts = append(exporterOpts, otlptracehttp.WithEndpointURL(url))
+			}
+			return otlptracehttp.New(ctx, exporterOpts...)
+		},
+		Stdout: func() (sdktrace.SpanExporter, error) {
+			return stdouttrace.New(stdouttrace.WithPrettyPrint())
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := opts.Resource()
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// NewPropagator returns the propagator used for incoming and outgoing requests.
+func NewPropagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	)
+}
+
+// Tracer returns the tracer shared by every layer of the service.
+func Tracer() trace.Tracer {
+	return otel.Tracer(telemetry.InstrumentationName)
+}
+
+// Start starts a span named after the calling layer and method, e.g. "service.Login".
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -1,15 +1,38 @@
 package validator
 
 import (
-	"regexp"
+	"reflect"
+	"time"
 
+	"github.com/SawitProRecruitment/UserService/lib/patch"
+	"github.com/SawitProRecruitment/UserService/lib/phone"
+	"golang.org/x/text/language"
 	"gopkg.in/go-playground/validator.v9"
 )
 
+// DateLayout is the format of dates like the date of birth.
+const DateLayout = "2006-01-02"
+
+type NewValidatorOptions struct {
+	PhoneParser *phone.Parser
+}
+
+// NewValidator creates a validator that only accepts Indonesian phone numbers.
 func NewValidator() *Validator {
+	return NewValidatorWithOptions(NewValidatorOptions{
+		PhoneParser: phone.NewParser(phone.DefaultOptions),
+	})
+}
+
+func NewValidatorWithOptions(opts NewValidatorOptions) *Validator {
 	validator := validator.New()
-	validator.RegisterValidation("customPassword", validateCustomPassword)
-	validator.RegisterValidation("customPhone", validateCustomPhone)
+	validator.RegisterValidation("customPhone", validateCustomPhone(opts.PhoneParser))
+	validator.RegisterValidation("customDateOfBirth", validateCustomDateOfBirth)
+	validator.RegisterValidation("customLocale", validateCustomLocale)
+	validator.RegisterValidation("customTimezone", validateCustomTimezone)
+	// Merge patch members are validated by their value, absent and null
+	// members are skipped by omitempty.
+	validator.RegisterCustomTypeFunc(patchFieldValue, patch.Field[string]{})
 	return &Validator{
 		validator: validator,
 	}
@@ -23,19 +46,37 @@ func (v *Validator) Validate(i interface{}) error {
 	return v.validator.Struct(i)
 }
 
-func validateCustomPassword(fl validator.FieldLevel) bool {
-	password := fl.Field().String()
-	hasUpperCase := regexp.MustCompile("[A-Z]").MatchString(password)
-	hasNumber := regexp.MustCompile("[0-9]").MatchString(password)
-	hasSpecial := regexp.MustCompile("[^A-Za-z0-9]").MatchString(password)
-	length := len(password) >= 6 && len(password) <= 64
+func validateCustomPhone(parser *phone.Parser) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return parser.Valid(fl.Field().String())
+	}
+}
+
+func validateCustomDateOfBirth(fl validator.FieldLevel) bool {
+	date, err := time.Parse(DateLayout, fl.Field().String())
+	return err == nil && date.Year() >= 1900 && date.Before(time.Now())
+}
 
-	return hasUpperCase && hasNumber && hasSpecial && length
+func validateCustomLocale(fl validator.FieldLevel) bool {
+	_, err := language.Parse(fl.Field().String())
+	return err == nil
 }
 
-func validateCustomPhone(fl validator.FieldLevel) bool {
-	phone := fl.Field().String()
-	regex := regexp.MustCompile(`^\+62[0-9]{10,13}$`)
+func validateCustomTimezone(fl validator.FieldLevel) bool {
+	name := fl.Field().String()
+	// LoadLocation accepts "" and "Local", which are not portable names.
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
 
-	return regex.MatchString(phone)
+// patchFieldValue returns a pointer, which omitempty does not skip when it
+// points to an empty string.
+func patchFieldValue(field reflect.Value) interface{} {
+	if v, ok := field.Interface().(patch.Field[string]).Get(); ok {
+		return &v
+	}
+	return nil
 }
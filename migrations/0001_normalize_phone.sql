@@ -0,0 +1,44 @@
+/**
+  Normalize existing phone numbers to E.164 (+<country code><number>).
+  Before this migration only Indonesian numbers were accepted, so national
+  numbers (0812...) and numbers without the plus sign (62812...) are assumed
+  to be Indonesian.
+  Rows whose canonical form is already taken by another user are left as they
+  are and have to be merged by hand, they are listed by:
+    SELECT id, phone FROM users WHERE phone !~ '^\+[1-9][0-9]{6,14}$';
+  */
+WITH "stripped" AS (
+  SELECT "id", regexp_replace("phone", '[^0-9+]', '', 'g') AS "phone"
+  FROM "users"
+),
+"canonical" AS (
+  SELECT "id",
+    CASE
+      WHEN "phone" LIKE '+%' THEN '+' || regexp_replace(substr("phone", 2), '^620', '62')
+      WHEN "phone" LIKE '00%' THEN '+' || substr("phone", 3)
+      WHEN "phone" LIKE '0%' THEN '+62' || substr("phone", 2)
+      WHEN "phone" LIKE '62%' THEN '+' || regexp_replace("phone", '^620', '62')
+      ELSE "phone"
+    END AS "phone"
+  FROM "stripped"
+),
+"unique_canonical" AS (
+  SELECT DISTINCT ON ("c"."phone") "c"."id", "c"."phone"
+  FROM "canonical" "c"
+  JOIN "users" "u" ON "u"."id" = "c"."id"
+  WHERE NOT EXISTS (
+    SELECT 1 FROM "users" "o" WHERE "o"."phone" = "c"."phone" AND "o"."id" <> "c"."id"
+  )
+  ORDER BY "c"."phone", "u"."created_at", "c"."id"
+)
+UPDATE "users"
+SET "phone" = "unique_canonical"."phone", "updated_at" = NOW()
+FROM "unique_canonical"
+WHERE "users"."id" = "unique_canonical"."id" AND "users"."phone" <> "unique_canonical"."phone";
+
+/**
+  UNIQUE ("phone") now applies to the canonical form: new rows must be E.164.
+  NOT VALID skips the check for rows that could not be normalized above.
+  */
+ALTER TABLE "users"
+  ADD CONSTRAINT "users_phone_e164" CHECK ("phone" ~ '^\+[1-9][0-9]{6,14}$') NOT VALID;
@@ -0,0 +1,19 @@
+/** Optional email, unique regardless of case, usable to login once verified. */
+ALTER TABLE "users"
+  ADD COLUMN "email" VARCHAR,
+  ADD COLUMN "email_verified_at" TIMESTAMPTZ(0);
+
+CREATE UNIQUE INDEX "users_email_key" ON "users" (lower("email"));
+
+/** One-time tokens sent by email, only their SHA-256 hash is stored. */
+CREATE TABLE IF NOT EXISTS "email_verifications" (
+  "id" BIGSERIAL NOT NULL PRIMARY KEY,
+  "user_id" BIGINT NOT NULL,
+  "email" VARCHAR NOT NULL,
+  "token_hash" VARCHAR NOT NULL,
+  "expires_at" TIMESTAMPTZ(0) NOT NULL,
+  "used_at" TIMESTAMPTZ(0),
+  "created_at" TIMESTAMPTZ(0),
+  UNIQUE ("token_hash"),
+  FOREIGN KEY ("user_id") REFERENCES "users" ("id")
+);
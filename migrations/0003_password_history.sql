@@ -0,0 +1,22 @@
+/** Previous password hashes, pruned to the configured history size on every change. */
+CREATE TABLE IF NOT EXISTS "password_history" (
+  "id" BIGSERIAL NOT NULL PRIMARY KEY,
+  "user_id" BIGINT NOT NULL,
+  "password" VARCHAR NOT NULL,
+  "created_at" TIMESTAMPTZ(0),
+  FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE
+);
+
+CREATE INDEX "password_history_user_id_idx" ON "password_history" ("user_id", "id" DESC);
+
+/** One-time password reset tokens sent by email, only their SHA-256 hash is stored. */
+CREATE TABLE IF NOT EXISTS "password_resets" (
+  "id" BIGSERIAL NOT NULL PRIMARY KEY,
+  "user_id" BIGINT NOT NULL,
+  "token_hash" VARCHAR NOT NULL,
+  "expires_at" TIMESTAMPTZ(0) NOT NULL,
+  "used_at" TIMESTAMPTZ(0),
+  "created_at" TIMESTAMPTZ(0),
+  UNIQUE ("token_hash"),
+  FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE
+);
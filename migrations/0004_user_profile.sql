@@ -0,0 +1,6 @@
+/** Optional profile fields, NULL when not set. */
+ALTER TABLE "users"
+  ADD COLUMN "date_of_birth" DATE,
+  ADD COLUMN "avatar_url" VARCHAR,
+  ADD COLUMN "locale" VARCHAR,
+  ADD COLUMN "timezone" VARCHAR;
@@ -0,0 +1,3 @@
+/** Incremented on every profile change, exposed as the ETag of the user. */
+ALTER TABLE "users"
+  ADD COLUMN "version" BIGINT NOT NULL DEFAULT 1;
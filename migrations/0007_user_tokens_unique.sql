@@ -0,0 +1,27 @@
+/**
+  One token row per user. Concurrent first logins used to insert several rows
+  for the same user: they are merged into the most recently updated one, which
+  keeps the sum of their login counts.
+  */
+WITH "ranked" AS (
+  SELECT "id",
+    ROW_NUMBER() OVER (PARTITION BY "user_id" ORDER BY "updated_at" DESC NULLS LAST, "id" DESC) AS "rank",
+    SUM("count_login") OVER (PARTITION BY "user_id") AS "total"
+  FROM "user_tokens"
+)
+UPDATE "user_tokens"
+SET "count_login" = "ranked"."total"
+FROM "ranked"
+WHERE "user_tokens"."id" = "ranked"."id" AND "ranked"."rank" = 1 AND "user_tokens"."count_login" <> "ranked"."total";
+
+DELETE FROM "user_tokens"
+WHERE "id" IN (
+  SELECT "id" FROM (
+    SELECT "id", ROW_NUMBER() OVER (PARTITION BY "user_id" ORDER BY "updated_at" DESC NULLS LAST, "id" DESC) AS "rank"
+    FROM "user_tokens"
+  ) "ranked"
+  WHERE "rank" > 1
+);
+
+ALTER TABLE "user_tokens"
+  ADD CONSTRAINT "user_tokens_user_id_key" UNIQUE ("user_id");
@@ -0,0 +1,129 @@
+// Package migrations applies the SQL files in this directory, in name order,
+// to a database created from database.sql. Each file runs once, in its own
+// transaction, and is recorded in the schema_migrations table. The sqlite
+// directory has the same migrations for SQLite, starting with the schema.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFiles embed.FS
+
+// lockID is an arbitrary key for pg_advisory_xact_lock, so that instances
+// starting together do not apply the same migration twice.
+const lockID = 7262019
+
+// dialect holds the statements that differ between databases.
+type dialect struct {
+	createTable string
+	// lock serializes migrations run by instances starting together, inside
+	// the transaction of each file.
+	lock    string
+	exists  string
+	applied string
+}
+
+var postgres = dialect{
+	createTable: `
+	CREATE TABLE IF NOT EXISTS "schema_migrations" (
+		"version" VARCHAR NOT NULL PRIMARY KEY,
+		"applied_at" TIMESTAMPTZ(0) NOT NULL DEFAULT NOW()
+	)`,
+	lock:    fmt.Sprintf("SELECT pg_advisory_xact_lock(%d)", lockID),
+	exists:  "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)",
+	applied: "INSERT INTO schema_migrations(version) VALUES ($1)",
+}
+
+// sqlite relies on the database being opened with _txlock=immediate, so each
+// transaction takes the write lock when it begins.
+var sqlite = dialect{
+	createTable: `
+	CREATE TABLE IF NOT EXISTS "schema_migrations" (
+		"version" VARCHAR NOT NULL PRIMARY KEY,
+		"applied_at" DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	exists:  "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)",
+	applied: "INSERT INTO schema_migrations(version) VALUES (?)",
+}
+
+// Up applies every migration that has not been applied yet.
+func Up(ctx context.Context, db *sql.DB) error {
+	return up(ctx, db, files, postgres)
+}
+
+// UpSQLite creates the schema of a SQLite database and applies every
+// migration that has not been applied yet.
+func UpSQLite(ctx context.Context, db *sql.DB) error {
+	sub, err := fs.Sub(sqliteFiles, "sqlite")
+	if err != nil {
+		return err
+	}
+	return up(ctx, db, sub, sqlite)
+}
+
+func up(ctx context.Context, db *sql.DB, files fs.FS, d dialect) error {
+	_, err := db.ExecContext(ctx, d.createTable)
+	if err != nil {
+		return fmt.Errorf("migrations: create schema_migrations: %w", err)
+	}
+
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := apply(ctx, db, files, d, name); err != nil {
+			return fmt.Errorf("migrations: %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func apply(ctx context.Context, db *sql.DB, files fs.FS, d dialect, name string) error {
+	version := strings.TrimSuffix(name, ".sql")
+	query, err := fs.ReadFile(files, name)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if d.lock != "" {
+		if _, err := tx.ExecContext(ctx, d.lock); err != nil {
+			return err
+		}
+	}
+	var applied bool
+	err = tx.QueryRowContext(ctx, d.exists, version).Scan(&applied)
+	if err != nil || applied {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, string(query)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, d.applied, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
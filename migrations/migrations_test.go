@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteMigrations(t *testing.T) {
+	t.Parallel()
+
+	t.Run("every migration has a SQLite version", func(t *testing.T) {
+		postgresNames, err := fs.Glob(files, "*.sql")
+		assert.NoError(t, err)
+		sqliteNames, err := fs.Glob(sqliteFiles, "sqlite/*.sql")
+		assert.NoError(t, err)
+
+		expected := []string{"sqlite/0000_initial.sql"}
+		for _, name := range postgresNames {
+			expected = append(expected, "sqlite/"+name)
+		}
+		assert.Equal(t, expected, sqliteNames)
+	})
+}
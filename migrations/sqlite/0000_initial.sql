@@ -0,0 +1,26 @@
+/**
+  SQLite version of database.sql. SQLite cannot add a constraint to an existing
+  table, so the E.164 check of 0001_normalize_phone is part of the table.
+  */
+CREATE TABLE IF NOT EXISTS "users" (
+  "id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  "name" VARCHAR NOT NULL,
+  "phone" VARCHAR NOT NULL,
+  "password" VARCHAR NOT NULL,
+  "created_at" DATETIME,
+  "updated_at" DATETIME,
+  CONSTRAINT "users_phone_key" UNIQUE ("phone"),
+  CONSTRAINT "users_phone_e164" CHECK (
+    "phone" GLOB '+[1-9]*' AND length("phone") BETWEEN 8 AND 16 AND substr("phone", 2) NOT GLOB '*[^0-9]*'
+  )
+);
+
+CREATE TABLE IF NOT EXISTS "user_tokens" (
+  "id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  "user_id" BIGINT NOT NULL,
+  "token" VARCHAR NOT NULL,
+  "count_login" INT NOT NULL,
+  "created_at" DATETIME,
+  "updated_at" DATETIME,
+  FOREIGN KEY ("user_id") REFERENCES "users" ("id")
+);
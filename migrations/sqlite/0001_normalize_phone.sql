@@ -0,0 +1,2 @@
+/** Nothing to normalize in a new database, the check is part of 0000_initial. */
+SELECT 1;
@@ -0,0 +1,18 @@
+/** Optional email, unique regardless of case, usable to login once verified. */
+ALTER TABLE "users" ADD COLUMN "email" VARCHAR;
+ALTER TABLE "users" ADD COLUMN "email_verified_at" DATETIME;
+
+CREATE UNIQUE INDEX "users_email_key" ON "users" (lower("email"));
+
+/** One-time tokens sent by email, only their SHA-256 hash is stored. */
+CREATE TABLE IF NOT EXISTS "email_verifications" (
+  "id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  "user_id" BIGINT NOT NULL,
+  "email" VARCHAR NOT NULL,
+  "token_hash" VARCHAR NOT NULL,
+  "expires_at" DATETIME NOT NULL,
+  "used_at" DATETIME,
+  "created_at" DATETIME,
+  CONSTRAINT "email_verifications_token_hash_key" UNIQUE ("token_hash"),
+  FOREIGN KEY ("user_id") REFERENCES "users" ("id")
+);
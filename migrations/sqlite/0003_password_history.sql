@@ -0,0 +1,22 @@
+/** Previous password hashes, pruned to the configured history size on every change. */
+CREATE TABLE IF NOT EXISTS "password_history" (
+  "id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  "user_id" BIGINT NOT NULL,
+  "password" VARCHAR NOT NULL,
+  "created_at" DATETIME,
+  FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE
+);
+
+CREATE INDEX "password_history_user_id_idx" ON "password_history" ("user_id", "id" DESC);
+
+/** One-time password reset tokens sent by email, only their SHA-256 hash is stored. */
+CREATE TABLE IF NOT EXISTS "password_resets" (
+  "id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  "user_id" BIGINT NOT NULL,
+  "token_hash" VARCHAR NOT NULL,
+  "expires_at" DATETIME NOT NULL,
+  "used_at" DATETIME,
+  "created_at" DATETIME,
+  CONSTRAINT "password_resets_token_hash_key" UNIQUE ("token_hash"),
+  FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE
+);
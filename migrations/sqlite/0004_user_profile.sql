@@ -0,0 +1,5 @@
+/** Optional profile fields, NULL when not set. */
+ALTER TABLE "users" ADD COLUMN "date_of_birth" DATE;
+ALTER TABLE "users" ADD COLUMN "avatar_url" VARCHAR;
+ALTER TABLE "users" ADD COLUMN "locale" VARCHAR;
+ALTER TABLE "users" ADD COLUMN "timezone" VARCHAR;
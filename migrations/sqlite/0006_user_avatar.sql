@@ -0,0 +1,2 @@
+/** Blob key prefix of the uploaded avatar thumbnails, NULL when none. */
+ALTER TABLE "users" ADD COLUMN "avatar_key" VARCHAR;
@@ -0,0 +1,2 @@
+/** One token row per user, a new database has no duplicates to merge. */
+CREATE UNIQUE INDEX "user_tokens_user_id_key" ON "user_tokens" ("user_id");
@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/lib/cache"
+	"github.com/SawitProRecruitment/UserService/lib/logger"
+	"github.com/SawitProRecruitment/UserService/lib/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/sync/singleflight"
+)
+
+// cachedRepository caches GetUserById and GetUserByPhone in front of another
+// repository, and invalidates a user on every write to it. Other methods go
+// straight to that repository. Cached users have no password hash, as the
+// cache may be shared: reads of WithCredentials go to the repository.
+type cachedRepository struct {
+	RepositoryInterface
+	cache cache.Cache
+	ttl   time.Duration
+	loads *singleflight.Group
+	// generation counts the invalidations, for loads to find out that they
+	// raced with one.
+	generation *atomic.Uint64
+	// hits and misses count the lookups of each cached method.
+	hits   metric.Int64Counter
+	misses metric.Int64Counter
+	// written collects the keys to invalidate once the transaction the
+	// repository is bound to ends, it is nil outside one.
+	written *[]string
+}
+
+type NewCachedRepositoryOptions struct {
+	Repository RepositoryInterface
+	Cache      cache.Cache
+	// TTL bounds how stale a cached user can be when it is written by
+	// another process or read from a lagging replica, defaults to 1 minute.
+	TTL time.Duration
+	// Meter defaults to metrics.Meter.
+	Meter metric.Meter
+}
+
+// NewCachedRepository returns opts.Repository with users cached in opts.Cache.
+// Concurrent misses of a user are collapsed into one load.
+func NewCachedRepository(opts NewCachedRepositoryOptions) RepositoryInterface {
+	if opts.TTL == 0 {
+		opts.TTL = time.Minute
+	}
+	if opts.Meter == nil {
+		opts.Meter = metrics.Meter()
+	}
+	hits, _ := opts.Meter.Int64Counter("repository.cache.hits",
+		metric.WithDescription("Lookups answered by the user cache."))
+	misses, _ := opts.Meter.Int64Counter("repository.cache.misses",
+		metric.WithDescription("Lookups the user cache sent to the database."))
+	return &cachedRepository{
+		RepositoryInterface: opts.Repository,
+		cache:               opts.Cache,
+		ttl:                 opts.TTL,
+		loads:               &singleflight.Group{},
+		generation:          &atomic.Uint64{},
+		hits:                hits,
+		misses:              misses,
+	}
+}
+
+func userKey(id int64) string {
+	return fmt.Sprintf("user:id:%d", id)
+}
+
+// phoneKey holds the id of the user with phone.
+func phoneKey(phone string) string {
+	return "user:phone:" + phone
+}
+
+type credentialsKey struct{}
+
+// WithCredentials returns a copy of ctx whose reads of users return their
+// password hash, which the user cache does not keep.
+func WithCredentials(ctx context.Context) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, true)
+}
+
+func withCredentials(ctx context.Context) bool {
+	return ctx.Value(credentialsKey{}) != nil
+}
+
+// bypass reports whether reads must see the database: in a transaction, after
+// a write of the request, and for the password hash.
+func (r *cachedRepository) bypass(ctx context.Context) bool {
+	return r.written != nil || wrote(ctx) || withCredentials(ctx)
+}
+
+func (r *cachedRepository) GetUserById(ctx context.Context, id int64) (*User, error) {
+	if r.bypass(ctx) {
+		return r.RepositoryInterface.GetUserById(ctx, id)
+	}
+	if user := r.cached(ctx, id); user != nil {
+		r.count(ctx, r.hits, "GetUserById")
+		return user, nil
+	}
+	r.count(ctx, r.misses, "GetUserById")
+	return r.load(ctx, userKey(id), false, func(ctx context.Context) (*User, error) {
+		return r.RepositoryInterface.GetUserById(ctx, id)
+	})
+}
+
+func (r *cachedRepository) GetUserByPhone(ctx context.Context, phone string) (*User, error) {
+	if r.bypass(ctx) {
+		return r.RepositoryInterface.GetUserByPhone(ctx, phone)
+	}
+	if value, ok := r.get(ctx, phoneKey(phone)); ok {
+		id, err := strconv.ParseInt(string(value), 10, 64)
+		// The user may have changed its phone since.
+		if user := r.cached(ctx, id); err == nil && user != nil && user.Phone == phone {
+			r.count(ctx, r.hits, "GetUserByPhone")
+			return user, nil
+		}
+	}
+	r.count(ctx, r.misses, "GetUserByPhone")
+	return r.load(ctx, phoneKey(phone), true, func(ctx context.Context) (*User, error) {
+		return r.RepositoryInterface.GetUserByPhone(ctx, phone)
+	})
+}
+
+// cached returns the cached user with id, or nil.
+func (r *cachedRepository) cached(ctx context.Context, id int64) *User {
+	value, ok := r.get(ctx, userKey(id))
+	if !ok {
+		return nil
+	}
+	user := &User{}
+	if err := json.Unmarshal(value, user); err != nil {
+		r.warn(ctx, "invalid cached user", err)
+		return nil
+	}
+	return user
+}
+
+// load runs fn once for concurrent callers with the same key and caches the
+// user it returns without its password hash, and its id under the key when
+// byPhone. It does not cache a missing user.
+func (r *cachedRepository) load(ctx context.Context, key string, byPhone bool, fn func(ctx context.Context) (*User, error)) (*User, error) {
+	value, err, _ := r.loads.Do(key, func() (any, error) {
+		generation := r.generation.Load()
+		// The load outlives a caller that gives up, the others still wait.
+		user, err := fn(context.WithoutCancel(ctx))
+		if err != nil || user == nil {
+			return nil, err
+		}
+		cached := *user
+		cached.Password = ""
+		value, err := json.Marshal(cached)
+		if err != nil {
+			return nil, err
+		}
+		entries := map[string][]byte{userKey(user.Id): value}
+		if byPhone {
+			entries[key] = []byte(strconv.FormatInt(user.Id, 10))
+		}
+		r.fill(ctx, generation, entries)
+		return value, nil
+	})
+	if err != nil || value == nil {
+		return nil, err
+	}
+	// Each caller gets a user of its own.
+	user := &User{}
+	return user, json.Unmarshal(value.([]byte), user)
+}
+
+func (r *cachedRepository) count(ctx context.Context, counter metric.Int64Counter, method string) {
+	counter.Add(ctx, 1, metric.WithAttributes(attribute.String("method", method)))
+}
+
+// get treats a failing cache as a miss.
+func (r *cachedRepository) get(ctx context.Context, key string) ([]byte, bool) {
+	value, ok, err := r.cache.Get(ctx, key)
+	if err != nil {
+		r.warn(ctx, "failed to read the user cache", err)
+		return nil, false
+	}
+	return value, ok
+}
+
+// fill caches entries read while generation was current. A write invalidated
+// since may be missing from them, and its invalidation may have run before
+// they were set, so they are then removed again.
+func (r *cachedRepository) fill(ctx context.Context, generation uint64, entries map[string][]byte) {
+	keys := make([]string, 0, len(entries))
+	for key, value := range entries {
+		if err := r.cache.Set(ctx, key, value, r.ttl); err != nil {
+			r.warn(ctx, "failed to write the user cache", err)
+		}
+		keys = append(keys, key)
+	}
+	if r.generation.Load() != generation {
+		r.delete(ctx, keys...)
+	}
+}
+
+// invalidate removes keys from the cache, or once the transaction ends.
+func (r *cachedRepository) invalidate(ctx context.Context, keys ...string) {
+	if r.written != nil {
+		*r.written = append(*r.written, keys...)
+		return
+	}
+	r.delete(ctx, keys...)
+}
+
+// delete is counted by generation before it runs, so that a load filling the
+// cache concurrently sees it.
+func (r *cachedRepository) delete(ctx context.Context, keys ...string) {
+	r.generation.Add(1)
+	if err := r.cache.Delete(ctx, keys...); err != nil {
+		r.warn(ctx, "failed to invalidate the user cache", err)
+	}
+}
+
+func (r *cachedRepository) warn(ctx context.Context, msg string, err error) {
+	logger.FromContext(ctx).Warn(msg, slog.String("error", err.Error()))
+}
+
+// WithTx reads from the transaction, and invalidates what it wrote once it
+// ends, when other callers can see it.
+func (r *cachedRepository) WithTx(ctx context.Context, fn func(repo RepositoryInterface) error) error {
+	written := r.written
+	if written == nil {
+		written = &[]string{}
+		defer func() {
+			if len(*written) > 0 {
+				r.delete(ctx, *written...)
+			}
+		}()
+	}
+	return r.RepositoryInterface.WithTx(ctx, func(repo RepositoryInterface) error {
+		tx := *r
+		tx.RepositoryInterface = repo
+		tx.written = written
+		return fn(&tx)
+	})
+}
+
+func (r *cachedRepository) UpdateProfile(ctx context.Context, payload ProfileUpdate) (*int64, error) {
+	defer r.invalidate(ctx, userKey(payload.Id))
+	return r.RepositoryInterface.UpdateProfile(ctx, payload)
+}
+
+func (r *cachedRepository) UpdateAvatar(ctx context.Context, id int64, key string) (*AvatarUpdated, error) {
+	defer r.invalidate(ctx, userKey(id))
+	return r.RepositoryInterface.UpdateAvatar(ctx, id, key)
+}
+
+func (r *cachedRepository) UpdatePassword(ctx context.Context, id int64, password string) error {
+	defer r.invalidate(ctx, userKey(id))
+	return r.RepositoryInterface.UpdatePassword(ctx, id, password)
+}
+
+func (r *cachedRepository) ChangePassword(ctx context.Context, payload PasswordChangePayload) error {
+	defer r.invalidate(ctx, userKey(payload.UserId))
+	return r.RepositoryInterface.ChangePassword(ctx, payload)
+}
+
+func (r *cachedRepository) InsertUser(ctx context.Context, user User) (*int64, error) {
+	defer r.invalidate(ctx, phoneKey(user.Phone))
+	return r.RepositoryInterface.InsertUser(ctx, user)
+}
+
+func (r *cachedRepository) VerifyEmail(ctx context.Context, tokenHash string) (*int64, error) {
+	id, err := r.RepositoryInterface.VerifyEmail(ctx, tokenHash)
+	if id != nil {
+		r.invalidate(ctx, userKey(*id))
+	}
+	return id, err
+}
@@ -0,0 +1,322 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/lib/cache"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+type cacheTest struct {
+	ctx    context.Context
+	next   *MockRepositoryInterface
+	repo   RepositoryInterface
+	reader *sdkmetric.ManualReader
+}
+
+func setupCache(t *testing.T, c cache.Cache) *cacheTest {
+	ctrl := gomock.NewController(t)
+	next := NewMockRepositoryInterface(ctrl)
+	if c == nil {
+		c = cache.NewLRU(cache.LRUOptions{Size: 10})
+	}
+	reader := sdkmetric.NewManualReader()
+	return &cacheTest{
+		ctx:  context.Background(),
+		next: next,
+		repo: NewCachedRepository(NewCachedRepositoryOptions{
+			Repository: next,
+			Cache:      c,
+			Meter:      sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"),
+		}),
+		reader: reader,
+	}
+}
+
+// counts returns the value of a counter by method.
+func (s *cacheTest) counts(t *testing.T, name string) map[string]int64 {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, s.reader.Collect(s.ctx, &rm))
+	counts := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			for _, point := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				method, _ := point.Attributes.Value(attribute.Key("method"))
+				counts[method.AsString()] = point.Value
+			}
+		}
+	}
+	return counts
+}
+
+// cachedUser has no password hash, which is not cached.
+func cachedUser() *User {
+	return &User{Id: 1, Name: "rotan", Phone: "+6281000000001", Version: 1}
+}
+
+func TestCachedRepository(t *testing.T) {
+	t.Run("caches users by id", func(t *testing.T) {
+		s := setupCache(t, nil)
+		s.next.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(cachedUser(), nil).Times(1)
+
+		for i := 0; i < 3; i++ {
+			user, err := s.repo.GetUserById(s.ctx, 1)
+			assert.NoError(t, err)
+			assert.Equal(t, cachedUser(), user)
+		}
+		assert.Equal(t, map[string]int64{"GetUserById": 2}, s.counts(t, "repository.cache.hits"))
+		assert.Equal(t, map[string]int64{"GetUserById": 1}, s.counts(t, "repository.cache.misses"))
+	})
+
+	t.Run("callers get users of their own", func(t *testing.T) {
+		s := setupCache(t, nil)
+		s.next.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(cachedUser(), nil)
+
+		user, _ := s.repo.GetUserById(s.ctx, 1)
+		user.Name = "changed"
+		user, _ = s.repo.GetUserById(s.ctx, 1)
+		assert.Equal(t, "rotan", user.Name)
+	})
+
+	t.Run("caches users by phone", func(t *testing.T) {
+		s := setupCache(t, nil)
+		s.next.EXPECT().GetUserByPhone(gomock.Any(), "+6281000000001").Return(cachedUser(), nil).Times(1)
+
+		for i := 0; i < 2; i++ {
+			user, err := s.repo.GetUserByPhone(s.ctx, "+6281000000001")
+			assert.NoError(t, err)
+			assert.Equal(t, cachedUser(), user)
+		}
+		user, err := s.repo.GetUserById(s.ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, cachedUser(), user)
+		assert.Equal(t, map[string]int64{"GetUserById": 1, "GetUserByPhone": 1}, s.counts(t, "repository.cache.hits"))
+	})
+
+	t.Run("does not cache missing users or errors", func(t *testing.T) {
+		s := setupCache(t, nil)
+		s.next.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(nil, nil).Times(2)
+		s.next.EXPECT().GetUserById(gomock.Any(), int64(2)).Return(nil, errors.New("down")).Times(2)
+
+		for i := 0; i < 2; i++ {
+			user, err := s.repo.GetUserById(s.ctx, 1)
+			assert.NoError(t, err)
+			assert.Nil(t, user)
+			_, err = s.repo.GetUserById(s.ctx, 2)
+			assert.EqualError(t, err, "down")
+		}
+	})
+
+	t.Run("collapses concurrent misses", func(t *testing.T) {
+		s := setupCache(t, nil)
+		release := make(chan struct{})
+		s.next.EXPECT().GetUserById(gomock.Any(), int64(1)).DoAndReturn(func(ctx context.Context, id int64) (*User, error) {
+			<-release
+			return cachedUser(), nil
+		}).Times(1)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				user, err := s.repo.GetUserById(s.ctx, 1)
+				assert.NoError(t, err)
+				assert.Equal(t, cachedUser(), user)
+			}()
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+	})
+
+	t.Run("writes invalidate the user", func(t *testing.T) {
+		writes := map[string]func(s *cacheTest){
+			"update profile": func(s *cacheTest) {
+				s.next.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(nil, nil)
+				s.repo.UpdateProfile(s.ctx, ProfileUpdate{Id: 1})
+			},
+			"update avatar": func(s *cacheTest) {
+				s.next.EXPECT().UpdateAvatar(gomock.Any(), int64(1), "key").Return(nil, nil)
+				s.repo.UpdateAvatar(s.ctx, 1, "key")
+			},
+			"update password": func(s *cacheTest) {
+				s.next.EXPECT().UpdatePassword(gomock.Any(), int64(1), "rehashed").Return(nil)
+				s.repo.UpdatePassword(s.ctx, 1, "rehashed")
+			},
+			"change password": func(s *cacheTest) {
+				s.next.EXPECT().ChangePassword(gomock.Any(), gomock.Any()).Return(nil)
+				s.repo.ChangePassword(s.ctx, PasswordChangePayload{UserId: 1})
+			},
+			"verify email": func(s *cacheTest) {
+				id := int64(1)
+				s.next.EXPECT().VerifyEmail(gomock.Any(), "hash").Return(&id, nil)
+				s.repo.VerifyEmail(s.ctx, "hash")
+			},
+			"failed write": func(s *cacheTest) {
+				s.next.EXPECT().UpdatePassword(gomock.Any(), int64(1), "rehashed").Return(context.DeadlineExceeded)
+				s.repo.UpdatePassword(s.ctx, 1, "rehashed")
+			},
+		}
+		for name, write := range writes {
+			t.Run(name, func(t *testing.T) {
+				s := setupCache(t, nil)
+				s.next.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(cachedUser(), nil).Times(2)
+				s.repo.GetUserById(s.ctx, 1)
+				write(s)
+				s.repo.GetUserById(s.ctx, 1)
+			})
+		}
+	})
+
+	t.Run("a changed phone no longer finds the user", func(t *testing.T) {
+		s := setupCache(t, nil)
+		s.next.EXPECT().GetUserByPhone(gomock.Any(), "+6281000000001").Return(cachedUser(), nil)
+		s.repo.GetUserByPhone(s.ctx, "+6281000000001")
+
+		changed := cachedUser()
+		changed.Phone = "+6281000000002"
+		s.next.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(nil, nil)
+		s.repo.UpdateProfile(s.ctx, ProfileUpdate{Id: 1, Phone: &changed.Phone})
+		s.next.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(changed, nil)
+		s.repo.GetUserById(s.ctx, 1)
+
+		s.next.EXPECT().GetUserByPhone(gomock.Any(), "+6281000000001").Return(nil, nil)
+		user, err := s.repo.GetUserByPhone(s.ctx, "+6281000000001")
+		assert.NoError(t, err)
+		assert.Nil(t, user)
+	})
+
+	t.Run("insert user invalidates its phone", func(t *testing.T) {
+		c := cache.NewLRU(cache.LRUOptions{Size: 10})
+		c.Set(context.Background(), phoneKey("+6281000000001"), []byte("7"), time.Minute)
+		s := setupCache(t, c)
+		id := int64(1)
+		s.next.EXPECT().InsertUser(gomock.Any(), gomock.Any()).Return(&id, nil)
+		s.repo.InsertUser(s.ctx, User{Phone: "+6281000000001"})
+
+		_, ok, _ := c.Get(s.ctx, phoneKey("+6281000000001"))
+		assert.False(t, ok)
+	})
+
+	t.Run("transactions read through and invalidate when they end", func(t *testing.T) {
+		s := setupCache(t, nil)
+		s.next.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(cachedUser(), nil).Times(3)
+		s.repo.GetUserById(s.ctx, 1)
+
+		s.next.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(repo RepositoryInterface) error) error {
+				return fn(s.next)
+			})
+		s.next.EXPECT().ChangePassword(gomock.Any(), gomock.Any()).Return(nil)
+		err := s.repo.WithTx(s.ctx, func(repo RepositoryInterface) error {
+			if _, err := repo.GetUserById(s.ctx, 1); err != nil {
+				return err
+			}
+			if err := repo.ChangePassword(s.ctx, PasswordChangePayload{UserId: 1}); err != nil {
+				return err
+			}
+			// Not yet committed, others still read the cached user.
+			_, ok, _ := s.repo.(*cachedRepository).cache.Get(s.ctx, userKey(1))
+			assert.True(t, ok)
+			return nil
+		})
+		assert.NoError(t, err)
+		s.repo.GetUserById(s.ctx, 1)
+	})
+
+	t.Run("reads of a request that wrote skip the cache", func(t *testing.T) {
+		s := setupCache(t, nil)
+		s.next.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(cachedUser(), nil).Times(2)
+		s.repo.GetUserById(s.ctx, 1)
+
+		ctx := WithReadYourWrites(s.ctx)
+		markWrite(ctx)
+		s.repo.GetUserById(ctx, 1)
+	})
+
+	t.Run("does not cache password hashes", func(t *testing.T) {
+		c := cache.NewLRU(cache.LRUOptions{Size: 10})
+		s := setupCache(t, c)
+		withHash := cachedUser()
+		withHash.Password = "hash"
+		s.next.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(withHash, nil)
+
+		user, err := s.repo.GetUserById(s.ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, cachedUser(), user)
+		value, _, _ := c.Get(s.ctx, userKey(1))
+		assert.NotContains(t, string(value), "hash")
+	})
+
+	t.Run("reads with credentials skip the cache", func(t *testing.T) {
+		s := setupCache(t, nil)
+		withHash := cachedUser()
+		withHash.Password = "hash"
+		s.next.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(withHash, nil).Times(2)
+		s.next.EXPECT().GetUserByPhone(gomock.Any(), "+6281000000001").Return(withHash, nil)
+		s.repo.GetUserById(s.ctx, 1)
+
+		ctx := WithCredentials(s.ctx)
+		user, err := s.repo.GetUserById(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "hash", user.Password)
+		user, err = s.repo.GetUserByPhone(ctx, "+6281000000001")
+		assert.NoError(t, err)
+		assert.Equal(t, "hash", user.Password)
+	})
+
+	t.Run("a load racing with a write does not cache the stale user", func(t *testing.T) {
+		s := setupCache(t, nil)
+		read, release := make(chan struct{}), make(chan struct{})
+		stale := cachedUser()
+		s.next.EXPECT().GetUserById(gomock.Any(), int64(1)).DoAndReturn(func(ctx context.Context, id int64) (*User, error) {
+			// Read before the write, returned after its invalidation.
+			close(read)
+			<-release
+			return stale, nil
+		})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			s.repo.GetUserById(s.ctx, 1)
+		}()
+
+		<-read
+		s.next.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(nil, nil)
+		s.repo.UpdateProfile(s.ctx, ProfileUpdate{Id: 1})
+		close(release)
+		<-done
+
+		fresh := cachedUser()
+		fresh.Name = "kebun"
+		s.next.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(fresh, nil)
+		user, err := s.repo.GetUserById(s.ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, fresh, user)
+	})
+
+	t.Run("a failing cache is a miss", func(t *testing.T) {
+		c := cache.NewMockCache(gomock.NewController(t))
+		c.EXPECT().Get(gomock.Any(), userKey(1)).Return(nil, false, errors.New("unreachable"))
+		c.EXPECT().Set(gomock.Any(), userKey(1), gomock.Any(), time.Minute).Return(errors.New("unreachable"))
+		s := setupCache(t, c)
+		s.next.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(cachedUser(), nil)
+
+		user, err := s.repo.GetUserById(s.ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, cachedUser(), user)
+	})
+}
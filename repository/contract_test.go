@@ -0,0 +1,562 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testContract checks the behaviour every RepositoryInterface implementation
+// has to share. newRepo returns an empty repository.
+func testContract(t *testing.T, newRepo func(t *testing.T) repository.RepositoryInterface) {
+	tests := map[string]func(t *testing.T, c *contract){
+		"users":          contractUsers,
+		"update profile": contractUpdateProfile,
+		"update avatar":  contractUpdateAvatar,
+		"passwords":      contractPasswords,
+		"tokens":         contractTokens,
+		"verify email":   contractVerifyEmail,
+		"password reset": contractPasswordReset,
+		"transactions":   contractTransactions,
+		"concurrency":    contractConcurrency,
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, &contract{ctx: context.Background(), repo: newRepo(t)})
+		})
+	}
+}
+
+type contract struct {
+	ctx  context.Context
+	repo repository.RepositoryInterface
+}
+
+func (c *contract) insertUser(t *testing.T, phone, email string) int64 {
+	id, err := c.repo.InsertUser(c.ctx, repository.User{Name: "rotan", Phone: phone, Password: "hash", Email: email})
+	require.NoError(t, err)
+	return *id
+}
+
+// concurrently runs fn n times at once and returns the errors.
+func concurrently(n int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+func assertConstraint(t *testing.T, err error, kind error, constraint string) {
+	var constraintErr *repository.ConstraintError
+	if assert.ErrorAs(t, err, &constraintErr) {
+		assert.ErrorIs(t, err, kind)
+		assert.Equal(t, constraint, constraintErr.Constraint)
+	}
+}
+
+func contractUsers(t *testing.T, c *contract) {
+	id := c.insertUser(t, "+6281000000001", "Rotan@Example.com")
+
+	t.Run("ids increase", func(t *testing.T) {
+		assert.Greater(t, c.insertUser(t, "+6281000000009", ""), id)
+	})
+
+	t.Run("get by id", func(t *testing.T) {
+		user, err := c.repo.GetUserById(c.ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, &repository.User{
+			Id:       id,
+			Name:     "rotan",
+			Phone:    "+6281000000001",
+			Password: "hash",
+			Email:    "Rotan@Example.com",
+			Version:  1,
+		}, user)
+	})
+
+	t.Run("get by phone", func(t *testing.T) {
+		user, err := c.repo.GetUserByPhone(c.ctx, "+6281000000001")
+		assert.NoError(t, err)
+		assert.Equal(t, id, user.Id)
+	})
+
+	t.Run("get by email ignores case", func(t *testing.T) {
+		user, err := c.repo.GetUserByEmail(c.ctx, "rotan@EXAMPLE.com")
+		assert.NoError(t, err)
+		assert.Equal(t, id, user.Id)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		user, err := c.repo.GetUserById(c.ctx, id+1000)
+		assert.NoError(t, err)
+		assert.Nil(t, user)
+		user, err = c.repo.GetUserByPhone(c.ctx, "+6281999999999")
+		assert.NoError(t, err)
+		assert.Nil(t, user)
+		user, err = c.repo.GetUserByEmail(c.ctx, "missing@example.com")
+		assert.NoError(t, err)
+		assert.Nil(t, user)
+	})
+
+	t.Run("users without email", func(t *testing.T) {
+		c.insertUser(t, "+6281000000002", "")
+		c.insertUser(t, "+6281000000003", "")
+		user, err := c.repo.GetUserByEmail(c.ctx, "")
+		assert.NoError(t, err)
+		assert.Nil(t, user)
+	})
+
+	t.Run("unique phone", func(t *testing.T) {
+		_, err := c.repo.InsertUser(c.ctx, repository.User{Name: "rotan", Phone: "+6281000000001", Password: "hash"})
+		assertConstraint(t, err, repository.ErrUniqueViolation, repository.ConstraintUserPhone)
+	})
+
+	t.Run("unique email ignores case", func(t *testing.T) {
+		_, err := c.repo.InsertUser(c.ctx, repository.User{Name: "rotan", Phone: "+6281000000004", Password: "hash", Email: "ROTAN@example.com"})
+		assertConstraint(t, err, repository.ErrUniqueViolation, repository.ConstraintUserEmail)
+	})
+
+	t.Run("phone must be E.164", func(t *testing.T) {
+		_, err := c.repo.InsertUser(c.ctx, repository.User{Name: "rotan", Phone: "081000000005", Password: "hash"})
+		assertConstraint(t, err, repository.ErrCheckViolation, repository.ConstraintUserPhoneE164)
+	})
+}
+
+func contractUpdateProfile(t *testing.T, c *contract) {
+	id := c.insertUser(t, "+6282000000001", "sawit@example.com")
+	c.insertUser(t, "+6282000000002", "taken@example.com")
+
+	t.Run("only given columns are written", func(t *testing.T) {
+		name := "kebun"
+		dateOfBirth := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+		version, err := c.repo.UpdateProfile(c.ctx, repository.ProfileUpdate{
+			Id:          id,
+			Name:        &name,
+			DateOfBirth: &sql.NullTime{Time: dateOfBirth, Valid: true},
+			Locale:      &sql.NullString{String: "id-ID", Valid: true},
+			Timezone:    &sql.NullString{String: "Asia/Jakarta", Valid: true},
+			AvatarURL:   &sql.NullString{String: "https://example.com/a.png", Valid: true},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), *version)
+
+		user, _ := c.repo.GetUserById(c.ctx, id)
+		assert.Equal(t, "kebun", user.Name)
+		assert.Equal(t, "+6282000000001", user.Phone)
+		assert.Equal(t, "sawit@example.com", user.Email)
+		assert.True(t, dateOfBirth.Equal(*user.DateOfBirth))
+		assert.Equal(t, "id-ID", user.Locale)
+		assert.Equal(t, "Asia/Jakarta", user.Timezone)
+		assert.Equal(t, "https://example.com/a.png", user.AvatarURL)
+	})
+
+	t.Run("null clears a column", func(t *testing.T) {
+		_, err := c.repo.UpdateProfile(c.ctx, repository.ProfileUpdate{Id: id, Locale: &sql.NullString{}})
+		assert.NoError(t, err)
+		user, _ := c.repo.GetUserById(c.ctx, id)
+		assert.Empty(t, user.Locale)
+		assert.Equal(t, "Asia/Jakarta", user.Timezone)
+	})
+
+	t.Run("changing the email resets its verification", func(t *testing.T) {
+		err := c.repo.InsertEmailVerification(c.ctx, repository.EmailVerificationPayloadInsert{
+			UserId:    id,
+			Email:     "sawit@example.com",
+			TokenHash: "verify",
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+		_, err = c.repo.VerifyEmail(c.ctx, "verify")
+		require.NoError(t, err)
+
+		_, err = c.repo.UpdateProfile(c.ctx, repository.ProfileUpdate{Id: id, Email: &sql.NullString{String: "SAWIT@example.com", Valid: true}})
+		assert.NoError(t, err)
+		user, _ := c.repo.GetUserById(c.ctx, id)
+		assert.NotNil(t, user.EmailVerifiedAt, "same email, other case")
+
+		_, err = c.repo.UpdateProfile(c.ctx, repository.ProfileUpdate{Id: id, Email: &sql.NullString{String: "kebun@example.com", Valid: true}})
+		assert.NoError(t, err)
+		user, _ = c.repo.GetUserById(c.ctx, id)
+		assert.Nil(t, user.EmailVerifiedAt)
+	})
+
+	t.Run("versions", func(t *testing.T) {
+		user, _ := c.repo.GetUserById(c.ctx, id)
+		name := "rotan"
+		version, err := c.repo.UpdateProfile(c.ctx, repository.ProfileUpdate{Id: id, Versions: []int64{user.Version - 1}, Name: &name})
+		assert.NoError(t, err)
+		assert.Nil(t, version)
+
+		version, err = c.repo.UpdateProfile(c.ctx, repository.ProfileUpdate{Id: id, Versions: []int64{user.Version - 1, user.Version}, Name: &name})
+		assert.NoError(t, err)
+		assert.Equal(t, user.Version+1, *version)
+	})
+
+	t.Run("a patch changing nothing keeps the version", func(t *testing.T) {
+		before, _ := c.repo.GetUserById(c.ctx, id)
+
+		version, err := c.repo.UpdateProfile(c.ctx, repository.ProfileUpdate{Id: id})
+		assert.NoError(t, err)
+		assert.Equal(t, before.Version, *version)
+
+		version, err = c.repo.UpdateProfile(c.ctx, repository.ProfileUpdate{Id: id, Versions: []int64{before.Version - 1}})
+		assert.NoError(t, err)
+		assert.Nil(t, version, "the versions are still checked")
+
+		version, err = c.repo.UpdateProfile(c.ctx, repository.ProfileUpdate{
+			Id:          id,
+			Versions:    []int64{before.Version},
+			Name:        &before.Name,
+			DateOfBirth: &sql.NullTime{Time: *before.DateOfBirth, Valid: true},
+			Locale:      &sql.NullString{},
+			Timezone:    &sql.NullString{String: before.Timezone, Valid: true},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, before.Version, *version)
+
+		after, _ := c.repo.GetUserById(c.ctx, id)
+		assert.Equal(t, before, after)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		name := "rotan"
+		version, err := c.repo.UpdateProfile(c.ctx, repository.ProfileUpdate{Id: id + 1000, Name: &name})
+		assert.NoError(t, err)
+		assert.Nil(t, version)
+
+		version, err = c.repo.UpdateProfile(c.ctx, repository.ProfileUpdate{Id: id + 1000})
+		assert.NoError(t, err)
+		assert.Nil(t, version)
+	})
+
+	t.Run("unique violations leave the user unchanged", func(t *testing.T) {
+		before, _ := c.repo.GetUserById(c.ctx, id)
+		phone := "+6282000000002"
+		_, err := c.repo.UpdateProfile(c.ctx, repository.ProfileUpdate{Id: id, Phone: &phone})
+		assertConstraint(t, err, repository.ErrUniqueViolation, repository.ConstraintUserPhone)
+
+		_, err = c.repo.UpdateProfile(c.ctx, repository.ProfileUpdate{Id: id, Email: &sql.NullString{String: "Taken@example.com", Valid: true}})
+		assertConstraint(t, err, repository.ErrUniqueViolation, repository.ConstraintUserEmail)
+
+		after, _ := c.repo.GetUserById(c.ctx, id)
+		assert.Equal(t, before, after)
+	})
+}
+
+func contractUpdateAvatar(t *testing.T, c *contract) {
+	id := c.insertUser(t, "+6283000000001", "")
+
+	updated, err := c.repo.UpdateAvatar(c.ctx, id, "1/first")
+	assert.NoError(t, err)
+	assert.Equal(t, &repository.AvatarUpdated{PreviousKey: "", Version: 2}, updated)
+
+	updated, err = c.repo.UpdateAvatar(c.ctx, id, "1/second")
+	assert.NoError(t, err)
+	assert.Equal(t, &repository.AvatarUpdated{PreviousKey: "1/first", Version: 3}, updated)
+
+	user, _ := c.repo.GetUserById(c.ctx, id)
+	assert.Equal(t, "1/second", user.AvatarKey)
+
+	updated, err = c.repo.UpdateAvatar(c.ctx, id+1000, "1/third")
+	assert.NoError(t, err)
+	assert.Nil(t, updated)
+}
+
+func contractPasswords(t *testing.T, c *contract) {
+	id := c.insertUser(t, "+6284000000001", "")
+
+	t.Run("update password", func(t *testing.T) {
+		assert.NoError(t, c.repo.UpdatePassword(c.ctx, id, "rehashed"))
+
+		user, _ := c.repo.GetUserById(c.ctx, id)
+		assert.Equal(t, "rehashed", user.Password)
+		history, err := c.repo.GetPasswordHistory(c.ctx, id, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, history)
+	})
+
+	t.Run("change password keeps a pruned history", func(t *testing.T) {
+		for _, password := range []string{"first", "second", "third"} {
+			err := c.repo.ChangePassword(c.ctx, repository.PasswordChangePayload{UserId: id, Password: password, HistorySize: 2})
+			assert.NoError(t, err)
+		}
+
+		user, _ := c.repo.GetUserById(c.ctx, id)
+		assert.Equal(t, "third", user.Password)
+		history, err := c.repo.GetPasswordHistory(c.ctx, id, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"second", "first"}, history)
+
+		history, err = c.repo.GetPasswordHistory(c.ctx, id, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"second"}, history)
+	})
+
+	t.Run("history of an unknown user", func(t *testing.T) {
+		history, err := c.repo.GetPasswordHistory(c.ctx, id+1000, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, history)
+	})
+}
+
+func contractTokens(t *testing.T, c *contract) {
+	id := c.insertUser(t, "+6285000000001", "")
+
+	token, err := c.repo.GetUserToken(c.ctx, id)
+	assert.NoError(t, err)
+	assert.Nil(t, token)
+
+	first, err := c.repo.UpsertToken(c.ctx, repository.TokenPayloadUpsert{UserId: id, Token: "first"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, first.CountLogin)
+
+	second, err := c.repo.UpsertToken(c.ctx, repository.TokenPayloadUpsert{UserId: id, Token: "second"})
+	assert.NoError(t, err)
+	assert.Equal(t, &repository.UserToken{Id: first.Id, UserId: id, Token: "second", CountLogin: 2}, second)
+
+	token, err = c.repo.GetUserToken(c.ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, second, token)
+
+	_, err = c.repo.UpsertToken(c.ctx, repository.TokenPayloadUpsert{UserId: id + 1000, Token: "token"})
+	assert.ErrorIs(t, err, repository.ErrForeignKeyViolation)
+}
+
+func contractVerifyEmail(t *testing.T, c *contract) {
+	id := c.insertUser(t, "+6286000000001", "sawit@example.com")
+	insert := func(t *testing.T, tokenHash, email string, expiresAt time.Time) {
+		err := c.repo.InsertEmailVerification(c.ctx, repository.EmailVerificationPayloadInsert{
+			UserId:    id,
+			Email:     email,
+			TokenHash: tokenHash,
+			ExpiresAt: expiresAt,
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("unknown token", func(t *testing.T) {
+		userId, err := c.repo.VerifyEmail(c.ctx, "unknown")
+		assert.NoError(t, err)
+		assert.Nil(t, userId)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		insert(t, "expired", "sawit@example.com", time.Now().Add(-time.Minute))
+		userId, err := c.repo.VerifyEmail(c.ctx, "expired")
+		assert.NoError(t, err)
+		assert.Nil(t, userId)
+	})
+
+	t.Run("token of a previous email", func(t *testing.T) {
+		insert(t, "previous", "old@example.com", time.Now().Add(time.Hour))
+		userId, err := c.repo.VerifyEmail(c.ctx, "previous")
+		assert.NoError(t, err)
+		assert.Nil(t, userId)
+	})
+
+	t.Run("verifies once", func(t *testing.T) {
+		insert(t, "valid", "SAWIT@example.com", time.Now().Add(time.Hour))
+
+		userId, err := c.repo.VerifyEmail(c.ctx, "valid")
+		assert.NoError(t, err)
+		assert.Equal(t, id, *userId)
+		user, _ := c.repo.GetUserById(c.ctx, id)
+		assert.WithinDuration(t, time.Now(), *user.EmailVerifiedAt, time.Minute)
+		assert.Equal(t, int64(2), user.Version)
+
+		userId, err = c.repo.VerifyEmail(c.ctx, "valid")
+		assert.NoError(t, err)
+		assert.Nil(t, userId)
+	})
+
+	t.Run("unique token", func(t *testing.T) {
+		err := c.repo.InsertEmailVerification(c.ctx, repository.EmailVerificationPayloadInsert{
+			UserId:    id,
+			Email:     "sawit@example.com",
+			TokenHash: "valid",
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+		assert.ErrorIs(t, err, repository.ErrUniqueViolation)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		err := c.repo.InsertEmailVerification(c.ctx, repository.EmailVerificationPayloadInsert{
+			UserId:    id + 1000,
+			Email:     "sawit@example.com",
+			TokenHash: "orphan",
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+		assert.ErrorIs(t, err, repository.ErrForeignKeyViolation)
+	})
+}
+
+func contractPasswordReset(t *testing.T, c *contract) {
+	id := c.insertUser(t, "+6287000000001", "")
+	insert := func(t *testing.T, tokenHash string, expiresAt time.Time) {
+		err := c.repo.InsertPasswordReset(c.ctx, repository.PasswordResetPayloadInsert{
+			UserId:    id,
+			TokenHash: tokenHash,
+			ExpiresAt: expiresAt,
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("unknown token", func(t *testing.T) {
+		userId, err := c.repo.GetPasswordReset(c.ctx, "unknown")
+		assert.NoError(t, err)
+		assert.Nil(t, userId)
+		userId, err = c.repo.UsePasswordReset(c.ctx, "unknown")
+		assert.NoError(t, err)
+		assert.Nil(t, userId)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		insert(t, "expired", time.Now().Add(-time.Minute))
+		userId, err := c.repo.GetPasswordReset(c.ctx, "expired")
+		assert.NoError(t, err)
+		assert.Nil(t, userId)
+		userId, err = c.repo.UsePasswordReset(c.ctx, "expired")
+		assert.NoError(t, err)
+		assert.Nil(t, userId)
+	})
+
+	t.Run("used once", func(t *testing.T) {
+		insert(t, "valid", time.Now().Add(time.Hour))
+		userId, err := c.repo.GetPasswordReset(c.ctx, "valid")
+		assert.NoError(t, err)
+		assert.Equal(t, id, *userId)
+
+		userId, err = c.repo.UsePasswordReset(c.ctx, "valid")
+		assert.NoError(t, err)
+		assert.Equal(t, id, *userId)
+
+		userId, err = c.repo.GetPasswordReset(c.ctx, "valid")
+		assert.NoError(t, err)
+		assert.Nil(t, userId)
+		userId, err = c.repo.UsePasswordReset(c.ctx, "valid")
+		assert.NoError(t, err)
+		assert.Nil(t, userId)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		err := c.repo.InsertPasswordReset(c.ctx, repository.PasswordResetPayloadInsert{
+			UserId:    id + 1000,
+			TokenHash: "orphan",
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+		assert.ErrorIs(t, err, repository.ErrForeignKeyViolation)
+	})
+}
+
+func contractTransactions(t *testing.T, c *contract) {
+	t.Run("rolled back on error", func(t *testing.T) {
+		rollback := fmt.Errorf("rollback")
+		err := c.repo.WithTx(c.ctx, func(tx repository.RepositoryInterface) error {
+			_, err := tx.InsertUser(c.ctx, repository.User{Name: "rotan", Phone: "+6288000000001", Password: "hash"})
+			require.NoError(t, err)
+			return rollback
+		})
+		assert.ErrorIs(t, err, rollback)
+
+		user, err := c.repo.GetUserByPhone(c.ctx, "+6288000000001")
+		assert.NoError(t, err)
+		assert.Nil(t, user)
+	})
+
+	t.Run("committed on success", func(t *testing.T) {
+		err := c.repo.WithTx(c.ctx, func(tx repository.RepositoryInterface) error {
+			id, err := tx.InsertUser(c.ctx, repository.User{Name: "rotan", Phone: "+6288000000002", Password: "hash"})
+			if err != nil {
+				return err
+			}
+			user, err := tx.GetUserById(c.ctx, *id)
+			require.NoError(t, err)
+			assert.Equal(t, "+6288000000002", user.Phone, "writes are visible inside the transaction")
+			return nil
+		})
+		assert.NoError(t, err)
+
+		user, err := c.repo.GetUserByPhone(c.ctx, "+6288000000002")
+		assert.NoError(t, err)
+		assert.NotNil(t, user)
+	})
+
+	t.Run("nested transactions join the outer one", func(t *testing.T) {
+		rollback := fmt.Errorf("rollback")
+		err := c.repo.WithTx(c.ctx, func(tx repository.RepositoryInterface) error {
+			err := tx.WithTx(c.ctx, func(nested repository.RepositoryInterface) error {
+				_, err := nested.InsertUser(c.ctx, repository.User{Name: "rotan", Phone: "+6288000000003", Password: "hash"})
+				return err
+			})
+			require.NoError(t, err)
+			return rollback
+		})
+		assert.ErrorIs(t, err, rollback)
+
+		user, err := c.repo.GetUserByPhone(c.ctx, "+6288000000003")
+		assert.NoError(t, err)
+		assert.Nil(t, user)
+	})
+}
+
+func contractConcurrency(t *testing.T, c *contract) {
+	t.Run("one of the users with the same phone is inserted", func(t *testing.T) {
+		errs := concurrently(10, func(i int) error {
+			_, err := c.repo.InsertUser(c.ctx, repository.User{
+				Name:     fmt.Sprintf("user %d", i),
+				Phone:    "+6289000000001",
+				Password: "hash",
+			})
+			return err
+		})
+
+		var inserted int
+		for _, err := range errs {
+			if err == nil {
+				inserted++
+				continue
+			}
+			assertConstraint(t, err, repository.ErrUniqueViolation, repository.ConstraintUserPhone)
+		}
+		assert.Equal(t, 1, inserted)
+	})
+
+	t.Run("logins are counted once each", func(t *testing.T) {
+		id := c.insertUser(t, "+6289000000002", "")
+		errs := concurrently(10, func(i int) error {
+			_, err := c.repo.UpsertToken(c.ctx, repository.TokenPayloadUpsert{UserId: id, Token: fmt.Sprint(i)})
+			return err
+		})
+		for _, err := range errs {
+			assert.NoError(t, err)
+		}
+		token, err := c.repo.GetUserToken(c.ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, 10, token.CountLogin)
+	})
+}
+
+func TestMemoryRepository(t *testing.T) {
+	t.Parallel()
+
+	testContract(t, func(t *testing.T) repository.RepositoryInterface {
+		return repository.NewMemoryRepository(repository.NewMemoryRepositoryOptions{})
+	})
+}
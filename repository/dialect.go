@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// Dialect is the SQL database a repository talks to. Queries are written for
+// Postgres and rewritten for the other dialects.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// Database is how to open the database of a DATABASE_URL.
+type Database struct {
+	Dialect Dialect
+	// Driver is the database/sql driver name, the SQLite one is registered by
+	// modernc.org/sqlite.
+	Driver string
+	DSN    string
+}
+
+// ParseDatabaseURL picks the dialect from the scheme of databaseURL:
+// postgres:// or postgresql:// for Postgres, and sqlite:// followed by the
+// path of the database file for SQLite, e.g. sqlite://users.db or
+// sqlite:///var/lib/users/users.db. Query parameters are passed to the driver.
+// A Postgres key=value DSN, e.g. "host=db user=users", is also accepted.
+func ParseDatabaseURL(databaseURL string) (Database, error) {
+	if keyValueDSN.MatchString(databaseURL) {
+		return Database{Dialect: Postgres, Driver: "postgres", DSN: databaseURL}, nil
+	}
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		// The error quotes the URL, password included.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return Database{}, fmt.Errorf("invalid database url: %w", err)
+	}
+	switch u.Scheme {
+	case "postgres", "postgresql":
+		return Database{Dialect: Postgres, Driver: "postgres", DSN: databaseURL}, nil
+	case "sqlite":
+		path := u.Host + u.Path
+		if u.Opaque != "" {
+			path = u.Opaque
+		}
+		if path == "" {
+			return Database{}, fmt.Errorf("invalid database url: missing the path of the SQLite file")
+		}
+		q := u.Query()
+		// Enforce foreign keys, wait for the write lock instead of failing,
+		// and take it when a transaction begins, as Postgres would block on
+		// the rows it locks.
+		q.Add("_pragma", "foreign_keys(1)")
+		q.Add("_pragma", "busy_timeout(5000)")
+		q.Add("_pragma", "journal_mode(WAL)")
+		q.Set("_txlock", "immediate")
+		// Write times in a format datetime() parses, Time.String by default
+		// is not one.
+		q.Set("_time_format", "sqlite")
+		return Database{Dialect: SQLite, Driver: "sqlite", DSN: "file:" + path + "?" + q.Encode()}, nil
+	default:
+		return Database{}, fmt.Errorf("unsupported database url scheme %q", u.Scheme)
+	}
+}
+
+var (
+	// keyValueDSN matches the start of a list of key=value settings, which a
+	// URL cannot start with.
+	keyValueDSN = regexp.MustCompile(`^\s*\w+\s*=`)
+	placeholder = regexp.MustCompile(`\$(\d+)`)
+	sqliteTerms = strings.NewReplacer(
+		// Timestamps are stored as text, compared once normalized to UTC.
+		"expires_at > NOW()", "datetime(expires_at) > datetime('now')",
+		"NOW()", "datetime('now')",
+		// Writes lock the whole database.
+		" FOR UPDATE", "",
+	)
+)
+
+// rebind rewrites a query written for Postgres.
+func (d Dialect) rebind(query string) string {
+	if d != SQLite {
+		return query
+	}
+	return sqliteTerms.Replace(placeholder.ReplaceAllString(query, "?${1}"))
+}
+
+func (d Dialect) system() attribute.KeyValue {
+	if d == SQLite {
+		return semconv.DBSystemNameSQLite
+	}
+	return semconv.DBSystemNamePostgreSQL
+}
+
+// bind returns db, rebinding its queries when the dialect needs it.
+func (d Dialect) bind(db dbtx) dbtx {
+	if d != SQLite {
+		return db
+	}
+	return rebinder{db: db, dialect: d}
+}
+
+type rebinder struct {
+	db      dbtx
+	dialect Dialect
+}
+
+func (r rebinder) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return r.db.ExecContext(ctx, r.dialect.rebind(query), args...)
+}
+
+func (r rebinder) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return r.db.QueryContext(ctx, r.dialect.rebind(query), args...)
+}
+
+func (r rebinder) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return r.db.QueryRowContext(ctx, r.dialect.rebind(query), args...)
+}
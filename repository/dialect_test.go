@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDatabaseURL(t *testing.T) {
+	t.Parallel()
+
+	t.Run("postgres", func(t *testing.T) {
+		for _, databaseURL := range []string{
+			"postgres://postgres:postgres@db:5432/database?sslmode=disable",
+			"postgresql://localhost/database",
+			"host=db port=5432 user=postgres password=postgres dbname=database sslmode=disable",
+		} {
+			database, err := ParseDatabaseURL(databaseURL)
+			assert.NoError(t, err)
+			assert.Equal(t, Database{Dialect: Postgres, Driver: "postgres", DSN: databaseURL}, database)
+		}
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		tests := map[string]string{
+			"sqlite://users.db":                "file:users.db?",
+			"sqlite://./data/users.db":         "file:./data/users.db?",
+			"sqlite:///var/lib/users/users.db": "file:/var/lib/users/users.db?",
+			"sqlite:users.db?cache=shared":     "file:users.db?",
+		}
+		for databaseURL, prefix := range tests {
+			database, err := ParseDatabaseURL(databaseURL)
+			assert.NoError(t, err)
+			assert.Equal(t, SQLite, database.Dialect)
+			assert.Equal(t, "sqlite", database.Driver)
+			assert.Contains(t, database.DSN, prefix, databaseURL)
+			assert.Contains(t, database.DSN, "_pragma=foreign_keys%281%29", databaseURL)
+			assert.Contains(t, database.DSN, "_txlock=immediate", databaseURL)
+			assert.Contains(t, database.DSN, "_time_format=sqlite", databaseURL)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, databaseURL := range []string{"mysql://localhost/database", "sqlite://", "users.db"} {
+			_, err := ParseDatabaseURL(databaseURL)
+			assert.Error(t, err, databaseURL)
+		}
+	})
+
+	t.Run("errors do not quote the password", func(t *testing.T) {
+		_, err := ParseDatabaseURL("postgres://users:s3cret@db:port/users")
+		assert.EqualError(t, err, `invalid database url: invalid port ":port" after host`)
+	})
+}
+
+func TestDialect_Rebind(t *testing.T) {
+	t.Parallel()
+
+	query := "UPDATE password_resets SET used_at = NOW() WHERE token_hash = $1 AND expires_at > NOW() AND id IN ($2, $10)"
+	assert.Equal(t, query, Postgres.rebind(query))
+	assert.Equal(t,
+		"UPDATE password_resets SET used_at = datetime('now') WHERE token_hash = ?1 AND datetime(expires_at) > datetime('now') AND id IN (?2, ?10)",
+		SQLite.rebind(query),
+	)
+	assert.Equal(t, "SELECT avatar_key FROM users WHERE id = ?1", SQLite.rebind("SELECT avatar_key FROM users WHERE id = $1 FOR UPDATE"))
+}
@@ -0,0 +1,141 @@
+// This file contains the errors returned by the repository layer, so callers
+// do not depend on the database driver.
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Kinds of ConstraintError.
+var (
+	ErrUniqueViolation     = errors.New("unique violation")
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrNotNullViolation    = errors.New("not null violation")
+	ErrCheckViolation      = errors.New("check violation")
+)
+
+// Constraints callers may need to tell apart.
+const (
+	ConstraintUserPhone     = "users_phone_key"
+	ConstraintUserEmail     = "users_email_key"
+	ConstraintUserPhoneE164 = "users_phone_e164"
+)
+
+// Other constraints of the schema, named as Postgres names them.
+const (
+	constraintTokenUser             = "user_tokens_user_id_fkey"
+	constraintTokenUserUnique       = "user_tokens_user_id_key"
+	constraintVerificationUser      = "email_verifications_user_id_fkey"
+	constraintVerificationTokenHash = "email_verifications_token_hash_key"
+	constraintResetUser             = "password_resets_user_id_fkey"
+	constraintResetTokenHash        = "password_resets_token_hash_key"
+)
+
+// ConstraintError is a write rejected by a database constraint. It matches its
+// Kind with errors.Is.
+type ConstraintError struct {
+	Kind       error
+	Constraint string
+	Err        error
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("%s on %s", e.Kind, e.Constraint)
+}
+
+func (e *ConstraintError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+// pqKinds maps the Postgres integrity constraint violation codes.
+var pqKinds = map[pq.ErrorCode]error{
+	"23505": ErrUniqueViolation,
+	"23503": ErrForeignKeyViolation,
+	"23502": ErrNotNullViolation,
+	"23514": ErrCheckViolation,
+}
+
+// sqliteError is implemented by the errors of modernc.org/sqlite, Code is the
+// extended result code.
+type sqliteError interface {
+	error
+	Code() int
+}
+
+// sqliteKinds maps the SQLite extended constraint result codes.
+var sqliteKinds = map[int]error{
+	2067: ErrUniqueViolation,     // SQLITE_CONSTRAINT_UNIQUE
+	1555: ErrUniqueViolation,     // SQLITE_CONSTRAINT_PRIMARYKEY
+	787:  ErrForeignKeyViolation, // SQLITE_CONSTRAINT_FOREIGNKEY
+	1299: ErrNotNullViolation,    // SQLITE_CONSTRAINT_NOTNULL
+	275:  ErrCheckViolation,      // SQLITE_CONSTRAINT_CHECK
+}
+
+// sqliteColumns names the unique constraints SQLite reports by their columns.
+var sqliteColumns = map[string]string{
+	"users.phone":                    ConstraintUserPhone,
+	"user_tokens.user_id":            constraintTokenUserUnique,
+	"email_verifications.token_hash": constraintVerificationTokenHash,
+	"password_resets.token_hash":     constraintResetTokenHash,
+}
+
+// translateError turns constraint violations into a ConstraintError, other
+// errors are returned unchanged.
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		kind, ok := pqKinds[pqErr.Code]
+		if !ok {
+			return err
+		}
+		return &ConstraintError{
+			Kind:       kind,
+			Constraint: pqErr.Constraint,
+			Err:        err,
+		}
+	}
+	var sqliteErr sqliteError
+	if errors.As(err, &sqliteErr) {
+		kind, ok := sqliteKinds[sqliteErr.Code()]
+		if !ok {
+			return err
+		}
+		return &ConstraintError{
+			Kind:       kind,
+			Constraint: sqliteConstraint(sqliteErr.Error()),
+			Err:        err,
+		}
+	}
+	return err
+}
+
+// sqliteConstraint finds the constraint at the end of a message such as "UNIQUE
+// constraint failed: users.phone", "UNIQUE constraint failed: index
+// 'users_email_key'" or "CHECK constraint failed: users_phone_e164". SQLite
+// does not name the violated foreign key.
+func sqliteConstraint(message string) string {
+	if i := strings.LastIndex(message, " ("); i >= 0 {
+		message = message[:i]
+	}
+	const marker = "constraint failed: "
+	i := strings.LastIndex(message, marker)
+	if i < 0 || strings.HasSuffix(message, "constraint failed") {
+		return ""
+	}
+	constraint := message[i+len(marker):]
+	if index, ok := strings.CutPrefix(constraint, "index "); ok {
+		return strings.Trim(index, "'")
+	}
+	if name, ok := sqliteColumns[constraint]; ok {
+		return name
+	}
+	return constraint
+}
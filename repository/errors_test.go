@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslateError(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unique violation", func(t *testing.T) {
+		pqErr := &pq.Error{Code: "23505", Constraint: ConstraintUserPhone}
+		err := translateError(fmt.Errorf("insert: %w", pqErr))
+
+		var constraintErr *ConstraintError
+		assert.ErrorAs(t, err, &constraintErr)
+		assert.Equal(t, ConstraintUserPhone, constraintErr.Constraint)
+		assert.ErrorIs(t, err, ErrUniqueViolation)
+		assert.NotErrorIs(t, err, ErrForeignKeyViolation)
+		assert.ErrorIs(t, err, pqErr)
+	})
+
+	t.Run("foreign key violation", func(t *testing.T) {
+		err := translateError(&pq.Error{Code: "23503", Constraint: "user_tokens_user_id_fkey"})
+		assert.ErrorIs(t, err, ErrForeignKeyViolation)
+	})
+
+	t.Run("other errors are unchanged", func(t *testing.T) {
+		pqErr := &pq.Error{Code: "40001"}
+		assert.Equal(t, error(pqErr), translateError(pqErr))
+		other := errors.New("other")
+		assert.Equal(t, other, translateError(other))
+		assert.Nil(t, translateError(nil))
+	})
+}
+
+// sqliteErr mimics the errors of modernc.org/sqlite.
+type sqliteErr struct {
+	code int
+	msg  string
+}
+
+func (e *sqliteErr) Error() string {
+	return fmt.Sprintf("constraint failed: %s (%d)", e.msg, e.code)
+}
+
+func (e *sqliteErr) Code() int { return e.code }
+
+func TestTranslateError_SQLite(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		err        *sqliteErr
+		kind       error
+		constraint string
+	}{
+		{"unique column", &sqliteErr{2067, "UNIQUE constraint failed: users.phone"}, ErrUniqueViolation, ConstraintUserPhone},
+		{"unique index", &sqliteErr{2067, "UNIQUE constraint failed: index 'users_email_key'"}, ErrUniqueViolation, ConstraintUserEmail},
+		{"check", &sqliteErr{275, "CHECK constraint failed: users_phone_e164"}, ErrCheckViolation, ConstraintUserPhoneE164},
+		{"foreign key", &sqliteErr{787, "FOREIGN KEY constraint failed"}, ErrForeignKeyViolation, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := translateError(fmt.Errorf("insert: %w", tt.err))
+
+			var constraintErr *ConstraintError
+			assert.ErrorAs(t, err, &constraintErr)
+			assert.ErrorIs(t, err, tt.kind)
+			assert.Equal(t, tt.constraint, constraintErr.Constraint)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	t.Run("other errors are unchanged", func(t *testing.T) {
+		busy := &sqliteErr{5, "database is locked"}
+		assert.Equal(t, error(busy), translateError(busy))
+	})
+}
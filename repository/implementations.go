@@ -3,71 +3,300 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 )
 
-func (r *repository) GetUserById(ctx context.Context, id int64) (*User, error) {
+const userColumns = "id, name, phone, password, email, email_verified_at, date_of_birth, avatar_url, avatar_key, locale, timezone, version"
+
+func scanUser(row *sql.Row) (*User, error) {
 	output := &User{}
-	err := r.Db.QueryRowContext(ctx, "SELECT id, name, phone, password FROM users WHERE id = $1", id).
-		Scan(&output.Id, &output.Name, &output.Phone, &output.Password)
+	var email, avatarURL, avatarKey, locale, timezone sql.NullString
+	var emailVerifiedAt, dateOfBirth sql.NullTime
+	err := row.Scan(&output.Id, &output.Name, &output.Phone, &output.Password,
+		&email, &emailVerifiedAt, &dateOfBirth, &avatarURL, &avatarKey, &locale, &timezone, &output.Version)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
+	output.Email = email.String
+	if emailVerifiedAt.Valid {
+		output.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	if dateOfBirth.Valid {
+		output.DateOfBirth = &dateOfBirth.Time
+	}
+	output.AvatarURL = avatarURL.String
+	output.AvatarKey = avatarKey.String
+	output.Locale = locale.String
+	output.Timezone = timezone.String
 	return output, nil
 }
 
-func (r *repository) GetUserByPhone(ctx context.Context, phone string) (*User, error) {
-	output := &User{}
-	err := r.Db.QueryRowContext(ctx, "SELECT id, name, phone, password FROM users WHERE phone = $1", phone).
-		Scan(&output.Id, &output.Name, &output.Phone, &output.Password)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+func (r *repository) GetUserById(ctx context.Context, id int64) (_ *User, err error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = $1"
+	ctx, end := r.startSpan(ctx, "GetUserById", query)
+	defer end(&err)
+
+	var user *User
+	err = r.read(ctx, func(db dbtx) (err error) {
+		user, err = scanUser(db.QueryRowContext(ctx, query, id))
+		return err
+	})
+	return user, err
+}
+
+func (r *repository) GetUserByPhone(ctx context.Context, phone string) (_ *User, err error) {
+	query := "SELECT " + userColumns + " FROM users WHERE phone = $1"
+	ctx, end := r.startSpan(ctx, "GetUserByPhone", query)
+	defer end(&err)
+
+	var user *User
+	err = r.read(ctx, func(db dbtx) (err error) {
+		user, err = scanUser(db.QueryRowContext(ctx, query, phone))
+		return err
+	})
+	return user, err
+}
+
+func (r *repository) GetUserByEmail(ctx context.Context, email string) (_ *User, err error) {
+	query := "SELECT " + userColumns + " FROM users WHERE lower(email) = lower($1)"
+	ctx, end := r.startSpan(ctx, "GetUserByEmail", query)
+	defer end(&err)
+
+	var user *User
+	err = r.read(ctx, func(db dbtx) (err error) {
+		user, err = scanUser(db.QueryRowContext(ctx, query, email))
+		return err
+	})
+	return user, err
+}
+
+// UpdateProfile returns the new version, or nil when the user does not exist
+// or its version is not one of payload.Versions. The version is only bumped
+// when a value changes, so that a patch changing nothing keeps the ETag.
+func (r *repository) UpdateProfile(ctx context.Context, payload ProfileUpdate) (_ *int64, err error) {
+	args := []any{payload.Id}
+	var set, changed []string
+	column := func(name string, value any) {
+		args = append(args, value)
+		set = append(set, fmt.Sprintf("%s = $%d", name, len(args)))
+		changed = append(changed, fmt.Sprintf("%s IS DISTINCT FROM $%d", name, len(args)))
+	}
+	if payload.Name != nil {
+		column("name", *payload.Name)
+	}
+	if payload.Phone != nil {
+		column("phone", *payload.Phone)
+	}
+	if payload.Email != nil {
+		column("email", *payload.Email)
+		set = append(set, fmt.Sprintf(
+			"email_verified_at = CASE WHEN lower(email) IS NOT DISTINCT FROM lower($%d) THEN email_verified_at END",
+			len(args),
+		))
+	}
+	if payload.DateOfBirth != nil {
+		column("date_of_birth", *payload.DateOfBirth)
+	}
+	if payload.AvatarURL != nil {
+		column("avatar_url", *payload.AvatarURL)
+	}
+	if payload.Locale != nil {
+		column("locale", *payload.Locale)
+	}
+	if payload.Timezone != nil {
+		column("timezone", *payload.Timezone)
+	}
+
+	where := "id = $1"
+	if len(payload.Versions) > 0 {
+		var placeholders []string
+		for _, version := range payload.Versions {
+			args = append(args, version)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
 		}
-		return nil, err
+		where += " AND version IN (" + strings.Join(placeholders, ", ") + ")"
 	}
 
+	if len(set) == 0 {
+		query := "SELECT version FROM users WHERE " + where
+		ctx, end := r.startSpan(ctx, "UpdateProfile", query)
+		defer end(&err)
+		return scanUserId(ctx, r.Db, query, args...)
+	}
+	// SET expressions see the row as it was before the update.
+	isChanged := strings.Join(changed, " OR ")
+	set = append(set,
+		"version = CASE WHEN "+isChanged+" THEN version + 1 ELSE version END",
+		"updated_at = CASE WHEN "+isChanged+" THEN NOW() ELSE updated_at END",
+	)
+	query := `
+	UPDATE users
+	SET
+	` + strings.Join(set, ",\n\t") + `
+	WHERE ` + where + `
+	RETURNING version;`
+	ctx, end := r.startSpan(ctx, "UpdateProfile", query)
+	defer end(&err)
+	markWrite(ctx)
+
+	return scanUserId(ctx, r.Db, query, args...)
+}
+
+// UpdateAvatar replaces the avatar key of a user and returns the previous one,
+// so its blobs can be removed, or nil when the user does not exist.
+func (r *repository) UpdateAvatar(ctx context.Context, id int64, key string) (_ *AvatarUpdated, err error) {
+	ctx, end := r.startSpan(ctx, "UpdateAvatar", "")
+	defer end(&err)
+
+	var output *AvatarUpdated
+	err = r.inTx(ctx, func(tx *repository) error {
+		var previousKey sql.NullString
+		err := tx.Db.QueryRowContext(ctx, "SELECT avatar_key FROM users WHERE id = $1 FOR UPDATE", id).
+			Scan(&previousKey)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+		output = &AvatarUpdated{PreviousKey: previousKey.String}
+		return tx.Db.QueryRowContext(ctx, `
+	UPDATE users
+	SET
+	avatar_key = $2,
+	version = version + 1,
+	updated_at = NOW()
+	WHERE id = $1
+	RETURNING version;`, id, key).Scan(&output.Version)
+	})
+	if err != nil {
+		return nil, err
+	}
 	return output, nil
 }
 
-func (r *repository) UpdateProfile(ctx context.Context, user User) error {
+func (r *repository) UpdatePassword(ctx context.Context, id int64, password string) (err error) {
 	query := `
 	UPDATE users
-	SET 
-	name = $2,
-	phone = $3,
+	SET
+	password = $2,
 	updated_at = NOW()
 	WHERE id = $1;`
+	ctx, end := r.startSpan(ctx, "UpdatePassword", query)
+	defer end(&err)
+	markWrite(ctx)
 
-	_, err := r.Db.ExecContext(ctx, query,
-		user.Id,
-		user.Name,
-		user.Phone,
-	)
+	_, err = r.Db.ExecContext(ctx, query, id, password)
 	return err
 }
 
-func (r *repository) InsertUser(ctx context.Context, user User) (*int64, error) {
+// ChangePassword moves the current password into the history, sets the new
+// one and prunes the history, all in one transaction.
+func (r *repository) ChangePassword(ctx context.Context, payload PasswordChangePayload) (err error) {
+	ctx, end := r.startSpan(ctx, "ChangePassword", "")
+	defer end(&err)
+
+	queries := []struct {
+		query string
+		args  []any
+	}{
+		{
+			query: `
+	INSERT INTO password_history(user_id, password, created_at)
+	SELECT id, password, NOW() FROM users WHERE id = $1 FOR UPDATE;`,
+			args: []any{payload.UserId},
+		},
+		{
+			query: `
+	UPDATE users
+	SET
+	password = $2,
+	updated_at = NOW()
+	WHERE id = $1;`,
+			args: []any{payload.UserId, payload.Password},
+		},
+		{
+			query: `
+	DELETE FROM password_history
+	WHERE user_id = $1 AND id NOT IN (
+		SELECT id FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2
+	);`,
+			args: []any{payload.UserId, payload.HistorySize},
+		},
+	}
+	return r.inTx(ctx, func(tx *repository) error {
+		for _, q := range queries {
+			if _, err := tx.Db.ExecContext(ctx, q.query, q.args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetPasswordHistory returns up to limit previous password hashes, newest first.
+func (r *repository) GetPasswordHistory(ctx context.Context, userId int64, limit int) (_ []string, err error) {
+	query := "SELECT password FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2"
+	ctx, end := r.startSpan(ctx, "GetPasswordHistory", query)
+	defer end(&err)
+
+	var passwords []string
+	err = r.read(ctx, func(db dbtx) error {
+		rows, err := db.QueryContext(ctx, query, userId, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		passwords = nil
+		for rows.Next() {
+			var password string
+			if err := rows.Scan(&password); err != nil {
+				return err
+			}
+			passwords = append(passwords, password)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return passwords, nil
+}
+
+func (r *repository) InsertUser(ctx context.Context, user User) (_ *int64, err error) {
 	var id int64
 	query := `
-	INSERT INTO users(id, name, password, phone, created_at, updated_at) VALUES
-	(DEFAULT, $1,$2,$3, NOW(), NOW()) RETURNING id;`
+	INSERT INTO users(name, password, phone, email, created_at, updated_at) VALUES
+	($1,$2,$3, NULLIF($4, ''), NOW(), NOW()) RETURNING id;`
+	ctx, end := r.startSpan(ctx, "InsertUser", query)
+	defer end(&err)
+	markWrite(ctx)
 
-	err := r.Db.QueryRowContext(ctx, query,
+	err = r.Db.QueryRowContext(ctx, query,
 		user.Name,
 		user.Password,
 		user.Phone,
+		user.Email,
 	).Scan(&id)
 
 	return &id, err
 }
 
-func (r *repository) GetUserToken(ctx context.Context, userId int64) (*UserToken, error) {
+func (r *repository) GetUserToken(ctx context.Context, userId int64) (_ *UserToken, err error) {
+	query := "SELECT id, user_id, token, count_login FROM user_tokens WHERE user_id = $1"
+	ctx, end := r.startSpan(ctx, "GetUserToken", query)
+	defer end(&err)
+
 	output := &UserToken{}
-	err := r.Db.QueryRowContext(ctx, "SELECT id, user_id, token, count_login FROM user_tokens WHERE user_id = $1", userId).
-		Scan(&output.Id, &output.UserId, &output.Token, &output.CountLogin)
+	err = r.read(ctx, func(db dbtx) error {
+		return db.QueryRowContext(ctx, query, userId).
+			Scan(&output.Id, &output.UserId, &output.Token, &output.CountLogin)
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -77,31 +306,138 @@ func (r *repository) GetUserToken(ctx context.Context, userId int64) (*UserToken
 	return output, nil
 }
 
-func (r *repository) InsertToken(ctx context.Context, payload TokenPayloadInsert) error {
+func (r *repository) UpsertToken(ctx context.Context, payload TokenPayloadUpsert) (_ *UserToken, err error) {
 	query := `
-	INSERT INTO user_tokens(id, user_id, token, count_login, created_at, updated_at) VALUES
-	(DEFAULT, $1,$2,1, NOW(), NOW())`
+	INSERT INTO user_tokens(user_id, token, count_login, created_at, updated_at) VALUES
+	($1,$2,1, NOW(), NOW())
+	ON CONFLICT (user_id) DO UPDATE SET
+	token = EXCLUDED.token,
+	count_login = user_tokens.count_login + 1,
+	updated_at = NOW()
+	RETURNING id, user_id, token, count_login`
+	ctx, end := r.startSpan(ctx, "UpsertToken", query)
+	defer end(&err)
+	markWrite(ctx)
 
-	_, err := r.Db.ExecContext(ctx, query,
+	output := &UserToken{}
+	err = r.Db.QueryRowContext(ctx, query,
 		payload.UserId,
 		payload.Token,
+	).Scan(&output.Id, &output.UserId, &output.Token, &output.CountLogin)
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (r *repository) InsertEmailVerification(ctx context.Context, payload EmailVerificationPayloadInsert) (err error) {
+	query := `
+	INSERT INTO email_verifications(user_id, email, token_hash, expires_at, created_at) VALUES
+	($1,$2,$3,$4, NOW())`
+	ctx, end := r.startSpan(ctx, "InsertEmailVerification", query)
+	defer end(&err)
+	markWrite(ctx)
+
+	_, err = r.Db.ExecContext(ctx, query,
+		payload.UserId,
+		payload.Email,
+		payload.TokenHash,
+		payload.ExpiresAt,
 	)
 	return err
+}
 
+// VerifyEmail consumes an unused, unexpired token and marks the email it was
+// sent to as verified, as long as the user still has that email. It returns
+// the user id, or nil if the token cannot be used.
+func (r *repository) VerifyEmail(ctx context.Context, tokenHash string) (_ *int64, err error) {
+	ctx, end := r.startSpan(ctx, "VerifyEmail", "")
+	defer end(&err)
+
+	var id *int64
+	err = r.inTx(ctx, func(tx *repository) error {
+		var userId int64
+		var email string
+		err := tx.Db.QueryRowContext(ctx, `
+	UPDATE email_verifications
+	SET used_at = NOW()
+	WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+	RETURNING user_id, email;`, tokenHash).Scan(&userId, &email)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+		id, err = scanUserId(ctx, tx.Db, `
+	UPDATE users
+	SET
+	email_verified_at = NOW(),
+	version = version + 1,
+	updated_at = NOW()
+	WHERE id = $1 AND lower(email) = lower($2)
+	RETURNING id;`, userId, email)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return id, nil
 }
 
-func (r *repository) UpdateToken(ctx context.Context, payload TokenPayloadUpdate) error {
+func (r *repository) InsertPasswordReset(ctx context.Context, payload PasswordResetPayloadInsert) (err error) {
 	query := `
-	UPDATE user_tokens
-	SET 
-	token = $2,
-	count_login = count_login + 1,
-	updated_at = NOW()
-	WHERE id = $1;`
+	INSERT INTO password_resets(user_id, token_hash, expires_at, created_at) VALUES
+	($1,$2,$3, NOW())`
+	ctx, end := r.startSpan(ctx, "InsertPasswordReset", query)
+	defer end(&err)
+	markWrite(ctx)
 
-	_, err := r.Db.ExecContext(ctx, query,
-		payload.Id,
-		payload.Token,
+	_, err = r.Db.ExecContext(ctx, query,
+		payload.UserId,
+		payload.TokenHash,
+		payload.ExpiresAt,
 	)
 	return err
 }
+
+// GetPasswordReset returns the user id of an unused, unexpired token, or nil.
+func (r *repository) GetPasswordReset(ctx context.Context, tokenHash string) (_ *int64, err error) {
+	query := "SELECT user_id FROM password_resets WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()"
+	ctx, end := r.startSpan(ctx, "GetPasswordReset", query)
+	defer end(&err)
+
+	var id *int64
+	err = r.read(ctx, func(db dbtx) (err error) {
+		id, err = scanUserId(ctx, db, query, tokenHash)
+		return err
+	})
+	return id, err
+}
+
+// UsePasswordReset consumes an unused, unexpired token. It returns the user
+// id, or nil if the token cannot be used.
+func (r *repository) UsePasswordReset(ctx context.Context, tokenHash string) (_ *int64, err error) {
+	query := `
+	UPDATE password_resets
+	SET used_at = NOW()
+	WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+	RETURNING user_id;`
+	ctx, end := r.startSpan(ctx, "UsePasswordReset", query)
+	defer end(&err)
+	markWrite(ctx)
+
+	return scanUserId(ctx, r.Db, query, tokenHash)
+}
+
+func scanUserId(ctx context.Context, db dbtx, query string, args ...any) (*int64, error) {
+	var id int64
+	err := db.QueryRowContext(ctx, query, args...).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &id, nil
+}
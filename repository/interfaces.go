@@ -8,12 +8,29 @@ import "context"
 
 //go:generate mockgen -source interfaces.go -destination interfaces.mock.gen.go
 type RepositoryInterface interface {
+	// WithTx runs fn atomically, every call made on repo is part of the same
+	// transaction which is rolled back if fn returns an error.
+	WithTx(ctx context.Context, fn func(repo RepositoryInterface) error) error
+
 	GetUserById(ctx context.Context, id int64) (*User, error)
 	GetUserByPhone(ctx context.Context, phone string) (*User, error)
-	UpdateProfile(ctx context.Context, user User) error
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	UpdateProfile(ctx context.Context, payload ProfileUpdate) (*int64, error)
+	UpdateAvatar(ctx context.Context, id int64, key string) (*AvatarUpdated, error)
+	UpdatePassword(ctx context.Context, id int64, password string) error
+	ChangePassword(ctx context.Context, payload PasswordChangePayload) error
+	GetPasswordHistory(ctx context.Context, userId int64, limit int) ([]string, error)
 	InsertUser(ctx context.Context, user User) (*int64, error)
 
 	GetUserToken(ctx context.Context, id int64) (*UserToken, error)
-	InsertToken(ctx context.Context, payload TokenPayloadInsert) error
-	UpdateToken(ctx context.Context, payload TokenPayloadUpdate) error
+	// UpsertToken atomically records a login of the user and returns the
+	// resulting token row.
+	UpsertToken(ctx context.Context, payload TokenPayloadUpsert) (*UserToken, error)
+
+	InsertEmailVerification(ctx context.Context, payload EmailVerificationPayloadInsert) error
+	VerifyEmail(ctx context.Context, tokenHash string) (*int64, error)
+
+	InsertPasswordReset(ctx context.Context, payload PasswordResetPayloadInsert) error
+	GetPasswordReset(ctx context.Context, tokenHash string) (*int64, error)
+	UsePasswordReset(ctx context.Context, tokenHash string) (*int64, error)
 }
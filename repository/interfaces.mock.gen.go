@@ -34,6 +34,65 @@ func (m *MockRepositoryInterface) EXPECT() *MockRepositoryInterfaceMockRecorder
 	return m.recorder
 }
 
+// ChangePassword mocks base method.
+func (m *MockRepositoryInterface) ChangePassword(ctx context.Context, payload PasswordChangePayload) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockRepositoryInterfaceMockRecorder) ChangePassword(ctx, payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockRepositoryInterface)(nil).ChangePassword), ctx, payload)
+}
+
+// GetPasswordHistory mocks base method.
+func (m *MockRepositoryInterface) GetPasswordHistory(ctx context.Context, userId int64, limit int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasswordHistory", ctx, userId, limit)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasswordHistory indicates an expected call of GetPasswordHistory.
+func (mr *MockRepositoryInterfaceMockRecorder) GetPasswordHistory(ctx, userId, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasswordHistory", reflect.TypeOf((*MockRepositoryInterface)(nil).GetPasswordHistory), ctx, userId, limit)
+}
+
+// GetPasswordReset mocks base method.
+func (m *MockRepositoryInterface) GetPasswordReset(ctx context.Context, tokenHash string) (*int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasswordReset", ctx, tokenHash)
+	ret0, _ := ret[0].(*int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasswordReset indicates an expected call of GetPasswordReset.
+func (mr *MockRepositoryInterfaceMockRecorder) GetPasswordReset(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasswordReset", reflect.TypeOf((*MockRepositoryInterface)(nil).GetPasswordReset), ctx, tokenHash)
+}
+
+// GetUserByEmail mocks base method.
+func (m *MockRepositoryInterface) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByEmail", ctx, email)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByEmail indicates an expected call of GetUserByEmail.
+func (mr *MockRepositoryInterfaceMockRecorder) GetUserByEmail(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockRepositoryInterface)(nil).GetUserByEmail), ctx, email)
+}
+
 // GetUserById mocks base method.
 func (m *MockRepositoryInterface) GetUserById(ctx context.Context, id int64) (*User, error) {
 	m.ctrl.T.Helper()
@@ -79,18 +138,32 @@ func (mr *MockRepositoryInterfaceMockRecorder) GetUserToken(ctx, id interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserToken", reflect.TypeOf((*MockRepositoryInterface)(nil).GetUserToken), ctx, id)
 }
 
-// InsertToken mocks base method.
-func (m *MockRepositoryInterface) InsertToken(ctx context.Context, payload TokenPayloadInsert) error {
+// InsertEmailVerification mocks base method.
+func (m *MockRepositoryInterface) InsertEmailVerification(ctx context.Context, payload EmailVerificationPayloadInsert) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InsertToken", ctx, payload)
+	ret := m.ctrl.Call(m, "InsertEmailVerification", ctx, payload)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// InsertToken indicates an expected call of InsertToken.
-func (mr *MockRepositoryInterfaceMockRecorder) InsertToken(ctx, payload interface{}) *gomock.Call {
+// InsertEmailVerification indicates an expected call of InsertEmailVerification.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertEmailVerification(ctx, payload interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertToken", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertToken), ctx, payload)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertEmailVerification", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertEmailVerification), ctx, payload)
+}
+
+// InsertPasswordReset mocks base method.
+func (m *MockRepositoryInterface) InsertPasswordReset(ctx context.Context, payload PasswordResetPayloadInsert) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertPasswordReset", ctx, payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertPasswordReset indicates an expected call of InsertPasswordReset.
+func (mr *MockRepositoryInterfaceMockRecorder) InsertPasswordReset(ctx, payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertPasswordReset", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertPasswordReset), ctx, payload)
 }
 
 // InsertUser mocks base method.
@@ -108,30 +181,105 @@ func (mr *MockRepositoryInterfaceMockRecorder) InsertUser(ctx, user interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertUser", reflect.TypeOf((*MockRepositoryInterface)(nil).InsertUser), ctx, user)
 }
 
-// UpdateProfile mocks base method.
-func (m *MockRepositoryInterface) UpdateProfile(ctx context.Context, user User) error {
+// UpdateAvatar mocks base method.
+func (m *MockRepositoryInterface) UpdateAvatar(ctx context.Context, id int64, key string) (*AvatarUpdated, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAvatar", ctx, id, key)
+	ret0, _ := ret[0].(*AvatarUpdated)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAvatar indicates an expected call of UpdateAvatar.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateAvatar(ctx, id, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAvatar", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateAvatar), ctx, id, key)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockRepositoryInterface) UpdatePassword(ctx context.Context, id int64, password string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateProfile", ctx, user)
+	ret := m.ctrl.Call(m, "UpdatePassword", ctx, id, password)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdatePassword(ctx, id, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdatePassword), ctx, id, password)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockRepositoryInterface) UpdateProfile(ctx context.Context, payload ProfileUpdate) (*int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, payload)
+	ret0, _ := ret[0].(*int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // UpdateProfile indicates an expected call of UpdateProfile.
-func (mr *MockRepositoryInterfaceMockRecorder) UpdateProfile(ctx, user interface{}) *gomock.Call {
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateProfile(ctx, payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateProfile), ctx, payload)
+}
+
+// UpsertToken mocks base method.
+func (m *MockRepositoryInterface) UpsertToken(ctx context.Context, payload TokenPayloadUpsert) (*UserToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertToken", ctx, payload)
+	ret0, _ := ret[0].(*UserToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertToken indicates an expected call of UpsertToken.
+func (mr *MockRepositoryInterfaceMockRecorder) UpsertToken(ctx, payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertToken", reflect.TypeOf((*MockRepositoryInterface)(nil).UpsertToken), ctx, payload)
+}
+
+// UsePasswordReset mocks base method.
+func (m *MockRepositoryInterface) UsePasswordReset(ctx context.Context, tokenHash string) (*int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsePasswordReset", ctx, tokenHash)
+	ret0, _ := ret[0].(*int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UsePasswordReset indicates an expected call of UsePasswordReset.
+func (mr *MockRepositoryInterfaceMockRecorder) UsePasswordReset(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsePasswordReset", reflect.TypeOf((*MockRepositoryInterface)(nil).UsePasswordReset), ctx, tokenHash)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockRepositoryInterface) VerifyEmail(ctx context.Context, tokenHash string) (*int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, tokenHash)
+	ret0, _ := ret[0].(*int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockRepositoryInterfaceMockRecorder) VerifyEmail(ctx, tokenHash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateProfile), ctx, user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockRepositoryInterface)(nil).VerifyEmail), ctx, tokenHash)
 }
 
-// UpdateToken mocks base method.
-func (m *MockRepositoryInterface) UpdateToken(ctx context.Context, payload TokenPayloadUpdate) error {
+// WithTx mocks base method.
+func (m *MockRepositoryInterface) WithTx(ctx context.Context, fn func(RepositoryInterface) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateToken", ctx, payload)
+	ret := m.ctrl.Call(m, "WithTx", ctx, fn)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpdateToken indicates an expected call of UpdateToken.
-func (mr *MockRepositoryInterfaceMockRecorder) UpdateToken(ctx, payload interface{}) *gomock.Call {
+// WithTx indicates an expected call of WithTx.
+func (mr *MockRepositoryInterfaceMockRecorder) WithTx(ctx, fn interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateToken", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateToken), ctx, payload)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockRepositoryInterface)(nil).WithTx), ctx, fn)
 }
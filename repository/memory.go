@@ -0,0 +1,421 @@
+// This file contains an in-memory implementation of the repository layer, for
+// tests and for running the service without a database.
+package repository
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+type memoryRepository struct {
+	// mu guards data, it is nil for a repository bound to a transaction,
+	// which already holds the lock of the repository that started it.
+	mu   *sync.Mutex
+	data *memoryData
+	now  func() time.Time
+}
+
+type memoryData struct {
+	lastUserId  int64
+	lastTokenId int64
+	users       map[int64]User
+	tokens      map[int64]UserToken // by user id
+	history     map[int64][]string  // by user id, oldest first
+	// verifications and resets are keyed by token hash.
+	verifications map[string]memoryToken
+	resets        map[string]memoryToken
+}
+
+type memoryToken struct {
+	userId    int64
+	email     string
+	expiresAt time.Time
+	used      bool
+}
+
+type NewMemoryRepositoryOptions struct {
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewMemoryRepository returns a thread-safe RepositoryInterface that keeps
+// everything in memory, with the constraints and results of the Postgres one.
+func NewMemoryRepository(opts NewMemoryRepositoryOptions) RepositoryInterface {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &memoryRepository{
+		mu: &sync.Mutex{},
+		data: &memoryData{
+			users:         map[int64]User{},
+			tokens:        map[int64]UserToken{},
+			history:       map[int64][]string{},
+			verifications: map[string]memoryToken{},
+			resets:        map[string]memoryToken{},
+		},
+		now: opts.Now,
+	}
+}
+
+func (r *memoryRepository) lock() func() {
+	if r.mu == nil {
+		return func() {}
+	}
+	r.mu.Lock()
+	return r.mu.Unlock
+}
+
+// timestamp returns the current time at the precision of TIMESTAMPTZ(0).
+func (r *memoryRepository) timestamp() time.Time {
+	return r.now().Round(time.Second)
+}
+
+// WithTx runs fn on a copy of the data, which replaces the data when fn
+// returns nil. Other callers wait until the transaction ends.
+func (r *memoryRepository) WithTx(ctx context.Context, fn func(repo RepositoryInterface) error) error {
+	if r.mu == nil {
+		return fn(r)
+	}
+	defer r.lock()()
+
+	tx := &memoryRepository{data: r.data.clone(), now: r.now}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	r.data = tx.data
+	return nil
+}
+
+func (d *memoryData) clone() *memoryData {
+	c := *d
+	c.users = make(map[int64]User, len(d.users))
+	for id, user := range d.users {
+		c.users[id] = user
+	}
+	c.tokens = make(map[int64]UserToken, len(d.tokens))
+	for id, token := range d.tokens {
+		c.tokens[id] = token
+	}
+	c.history = make(map[int64][]string, len(d.history))
+	for id, passwords := range d.history {
+		c.history[id] = append([]string(nil), passwords...)
+	}
+	c.verifications = make(map[string]memoryToken, len(d.verifications))
+	for hash, token := range d.verifications {
+		c.verifications[hash] = token
+	}
+	c.resets = make(map[string]memoryToken, len(d.resets))
+	for hash, token := range d.resets {
+		c.resets[hash] = token
+	}
+	return &c
+}
+
+func (r *memoryRepository) GetUserById(ctx context.Context, id int64) (*User, error) {
+	defer r.lock()()
+
+	user, ok := r.data.users[id]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func (r *memoryRepository) GetUserByPhone(ctx context.Context, phone string) (*User, error) {
+	defer r.lock()()
+
+	for _, user := range r.data.users {
+		if user.Phone == phone {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	defer r.lock()()
+
+	if id, ok := r.data.userIdByEmail(email); ok {
+		user := r.data.users[id]
+		return &user, nil
+	}
+	return nil, nil
+}
+
+func (d *memoryData) userIdByEmail(email string) (int64, bool) {
+	if email == "" {
+		return 0, false
+	}
+	for id, user := range d.users {
+		if strings.EqualFold(user.Email, email) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// checkUser applies the constraints of the users table to user.
+func (d *memoryData) checkUser(user User) error {
+	if !e164.MatchString(user.Phone) {
+		return &ConstraintError{Kind: ErrCheckViolation, Constraint: ConstraintUserPhoneE164}
+	}
+	for id, other := range d.users {
+		if id == user.Id {
+			continue
+		}
+		if other.Phone == user.Phone {
+			return &ConstraintError{Kind: ErrUniqueViolation, Constraint: ConstraintUserPhone}
+		}
+		if user.Email != "" && strings.EqualFold(other.Email, user.Email) {
+			return &ConstraintError{Kind: ErrUniqueViolation, Constraint: ConstraintUserEmail}
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) UpdateProfile(ctx context.Context, payload ProfileUpdate) (*int64, error) {
+	defer r.lock()()
+
+	user, ok := r.data.users[payload.Id]
+	if !ok {
+		return nil, nil
+	}
+	if len(payload.Versions) > 0 && !containsVersion(payload.Versions, user.Version) {
+		return nil, nil
+	}
+	before := user
+	if payload.Name != nil {
+		user.Name = *payload.Name
+	}
+	if payload.Phone != nil {
+		user.Phone = *payload.Phone
+	}
+	if payload.Email != nil {
+		if !strings.EqualFold(user.Email, payload.Email.String) {
+			user.EmailVerifiedAt = nil
+		}
+		user.Email = payload.Email.String
+	}
+	if payload.DateOfBirth != nil {
+		user.DateOfBirth = nil
+		if payload.DateOfBirth.Valid {
+			year, month, day := payload.DateOfBirth.Time.Date()
+			dateOfBirth := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+			user.DateOfBirth = &dateOfBirth
+		}
+	}
+	if payload.AvatarURL != nil {
+		user.AvatarURL = payload.AvatarURL.String
+	}
+	if payload.Locale != nil {
+		user.Locale = payload.Locale.String
+	}
+	if payload.Timezone != nil {
+		user.Timezone = payload.Timezone.String
+	}
+	if reflect.DeepEqual(before, user) {
+		return &user.Version, nil
+	}
+	if err := r.data.checkUser(user); err != nil {
+		return nil, err
+	}
+	user.Version++
+	r.data.users[user.Id] = user
+	return &user.Version, nil
+}
+
+func containsVersion(versions []int64, version int64) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryRepository) UpdateAvatar(ctx context.Context, id int64, key string) (*AvatarUpdated, error) {
+	defer r.lock()()
+
+	user, ok := r.data.users[id]
+	if !ok {
+		return nil, nil
+	}
+	output := &AvatarUpdated{PreviousKey: user.AvatarKey}
+	user.AvatarKey = key
+	user.Version++
+	r.data.users[id] = user
+	output.Version = user.Version
+	return output, nil
+}
+
+func (r *memoryRepository) UpdatePassword(ctx context.Context, id int64, password string) error {
+	defer r.lock()()
+
+	if user, ok := r.data.users[id]; ok {
+		user.Password = password
+		r.data.users[id] = user
+	}
+	return nil
+}
+
+func (r *memoryRepository) ChangePassword(ctx context.Context, payload PasswordChangePayload) error {
+	defer r.lock()()
+
+	user, ok := r.data.users[payload.UserId]
+	if !ok {
+		return nil
+	}
+	history := append(r.data.history[user.Id], user.Password)
+	if len(history) > payload.HistorySize {
+		history = history[len(history)-payload.HistorySize:]
+	}
+	r.data.history[user.Id] = history
+	user.Password = payload.Password
+	r.data.users[user.Id] = user
+	return nil
+}
+
+func (r *memoryRepository) GetPasswordHistory(ctx context.Context, userId int64, limit int) ([]string, error) {
+	defer r.lock()()
+
+	var passwords []string
+	history := r.data.history[userId]
+	for i := len(history) - 1; i >= 0 && len(passwords) < limit; i-- {
+		passwords = append(passwords, history[i])
+	}
+	return passwords, nil
+}
+
+func (r *memoryRepository) InsertUser(ctx context.Context, user User) (*int64, error) {
+	defer r.lock()()
+
+	if err := r.data.checkUser(User{Phone: user.Phone, Email: user.Email}); err != nil {
+		return nil, err
+	}
+	r.data.lastUserId++
+	id := r.data.lastUserId
+	r.data.users[id] = User{
+		Id:       id,
+		Name:     user.Name,
+		Phone:    user.Phone,
+		Password: user.Password,
+		Email:    user.Email,
+		Version:  1,
+	}
+	return &id, nil
+}
+
+func (r *memoryRepository) GetUserToken(ctx context.Context, userId int64) (*UserToken, error) {
+	defer r.lock()()
+
+	token, ok := r.data.tokens[userId]
+	if !ok {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+func (r *memoryRepository) UpsertToken(ctx context.Context, payload TokenPayloadUpsert) (*UserToken, error) {
+	defer r.lock()()
+
+	if _, ok := r.data.users[payload.UserId]; !ok {
+		return nil, &ConstraintError{Kind: ErrForeignKeyViolation, Constraint: constraintTokenUser}
+	}
+	token, ok := r.data.tokens[payload.UserId]
+	if !ok {
+		r.data.lastTokenId++
+		token = UserToken{Id: r.data.lastTokenId, UserId: payload.UserId}
+	}
+	token.Token = payload.Token
+	token.CountLogin++
+	r.data.tokens[payload.UserId] = token
+	return &token, nil
+}
+
+func (r *memoryRepository) InsertEmailVerification(ctx context.Context, payload EmailVerificationPayloadInsert) error {
+	defer r.lock()()
+
+	if _, ok := r.data.users[payload.UserId]; !ok {
+		return &ConstraintError{Kind: ErrForeignKeyViolation, Constraint: constraintVerificationUser}
+	}
+	if _, ok := r.data.verifications[payload.TokenHash]; ok {
+		return &ConstraintError{Kind: ErrUniqueViolation, Constraint: constraintVerificationTokenHash}
+	}
+	r.data.verifications[payload.TokenHash] = memoryToken{
+		userId:    payload.UserId,
+		email:     payload.Email,
+		expiresAt: payload.ExpiresAt,
+	}
+	return nil
+}
+
+func (r *memoryRepository) VerifyEmail(ctx context.Context, tokenHash string) (*int64, error) {
+	defer r.lock()()
+
+	verification, ok := r.useToken(r.data.verifications, tokenHash)
+	if !ok {
+		return nil, nil
+	}
+	user, ok := r.data.users[verification.userId]
+	if !ok || !strings.EqualFold(user.Email, verification.email) {
+		return nil, nil
+	}
+	verifiedAt := r.timestamp()
+	user.EmailVerifiedAt = &verifiedAt
+	user.Version++
+	r.data.users[user.Id] = user
+	return &user.Id, nil
+}
+
+// useToken marks an unused, unexpired token as used.
+func (r *memoryRepository) useToken(tokens map[string]memoryToken, tokenHash string) (memoryToken, bool) {
+	token, ok := tokens[tokenHash]
+	if !ok || token.used || !token.expiresAt.After(r.now()) {
+		return memoryToken{}, false
+	}
+	token.used = true
+	tokens[tokenHash] = token
+	return token, true
+}
+
+func (r *memoryRepository) InsertPasswordReset(ctx context.Context, payload PasswordResetPayloadInsert) error {
+	defer r.lock()()
+
+	if _, ok := r.data.users[payload.UserId]; !ok {
+		return &ConstraintError{Kind: ErrForeignKeyViolation, Constraint: constraintResetUser}
+	}
+	if _, ok := r.data.resets[payload.TokenHash]; ok {
+		return &ConstraintError{Kind: ErrUniqueViolation, Constraint: constraintResetTokenHash}
+	}
+	r.data.resets[payload.TokenHash] = memoryToken{
+		userId:    payload.UserId,
+		expiresAt: payload.ExpiresAt,
+	}
+	return nil
+}
+
+func (r *memoryRepository) GetPasswordReset(ctx context.Context, tokenHash string) (*int64, error) {
+	defer r.lock()()
+
+	reset, ok := r.data.resets[tokenHash]
+	if !ok || reset.used || !reset.expiresAt.After(r.now()) {
+		return nil, nil
+	}
+	return &reset.userId, nil
+}
+
+func (r *memoryRepository) UsePasswordReset(ctx context.Context, tokenHash string) (*int64, error) {
+	defer r.lock()()
+
+	reset, ok := r.useToken(r.data.resets, tokenHash)
+	if !ok {
+		return nil, nil
+	}
+	return &reset.userId, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type OpenOptions struct {
+	// MaxOpenConns is the size of the pool, 0 for no limit.
+	MaxOpenConns int
+	// MaxIdleConns is how many connections the pool keeps when idle, at most
+	// MaxOpenConns.
+	MaxIdleConns int
+	// ConnMaxLifetime closes connections older than it, 0 to reuse them
+	// forever.
+	ConnMaxLifetime time.Duration
+	// ConnectTimeout is how long to retry pinging the database, e.g. while it
+	// starts next to the service. It must be positive.
+	ConnectTimeout time.Duration
+	// ReloadDSN, when set, returns the DSN of each new connection in place of
+	// the one of the database, e.g. read again from a rotated secret file.
+	ReloadDSN func() (string, error)
+}
+
+// Backoff between pings of Open.
+const (
+	pingBackoffMin = 100 * time.Millisecond
+	pingBackoffMax = 5 * time.Second
+)
+
+// Open opens database with the pool of opts, and pings it until it answers or
+// opts.ConnectTimeout elapses, waiting twice as long after each failure. It
+// gives up at once when the database rejects the credentials or does not
+// exist, as retrying would not help.
+func Open(ctx context.Context, database Database, opts OpenOptions) (*sql.DB, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(database.Driver, database.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	if opts.ReloadDSN != nil {
+		drv := db.Driver()
+		db.Close()
+		db = sql.OpenDB(reloadConnector{driver: drv, reload: opts.ReloadDSN})
+	}
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+
+	if err := ping(ctx, db, opts.ConnectTimeout); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// reloadConnector opens each connection with the DSN reload returns then.
+type reloadConnector struct {
+	driver driver.Driver
+	reload func() (string, error)
+}
+
+func (c reloadConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dsn, err := c.reload()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := c.driver.(driver.DriverContext); ok {
+		connector, err := d.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return connector.Connect(ctx)
+	}
+	return c.driver.Open(dsn)
+}
+
+func (c reloadConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Validate reports the settings of the pool that are out of range or
+// contradict each other.
+func (opts OpenOptions) Validate() error {
+	var problems []string
+	if opts.MaxOpenConns < 0 {
+		problems = append(problems, fmt.Sprintf("max open connections must not be negative, got %d", opts.MaxOpenConns))
+	}
+	if opts.MaxIdleConns < 0 {
+		problems = append(problems, fmt.Sprintf("max idle connections must not be negative, got %d", opts.MaxIdleConns))
+	}
+	if opts.MaxOpenConns > 0 && opts.MaxIdleConns > opts.MaxOpenConns {
+		problems = append(problems, fmt.Sprintf("max idle connections (%d) must not exceed max open connections (%d)",
+			opts.MaxIdleConns, opts.MaxOpenConns))
+	}
+	if opts.ConnMaxLifetime < 0 {
+		problems = append(problems, fmt.Sprintf("connection max lifetime must not be negative, got %s", opts.ConnMaxLifetime))
+	}
+	if opts.ConnectTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("connect timeout must be positive, got %s", opts.ConnectTimeout))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid database pool: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func ping(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := pingBackoffMin
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if permanent(err) {
+			return fmt.Errorf("connect to database: %w", err)
+		}
+		slog.WarnContext(ctx, "database not reachable, retrying",
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			slog.String("error", err.Error()),
+		)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("connect to database: no answer within %s: %w", timeout, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, pingBackoffMax)
+	}
+}
+
+// permanent reports whether a failed ping is a misconfiguration: invalid
+// credentials or a database that does not exist.
+func permanent(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "28", "3D":
+			return true
+		}
+	}
+	return false
+}
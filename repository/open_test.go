@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pingDriver is a database/sql driver whose connections answer pings with the
+// errors of its DSN in turn, then succeed.
+type pingDriver struct {
+	errs  map[string][]error
+	pings map[string]*atomic.Int32
+}
+
+var fakeDriver = &pingDriver{errs: map[string][]error{}, pings: map[string]*atomic.Int32{}}
+
+func init() {
+	sql.Register("ping", fakeDriver)
+}
+
+func (d *pingDriver) Open(dsn string) (driver.Conn, error) {
+	return &pingConn{driver: d, dsn: dsn}, nil
+}
+
+type pingConn struct {
+	driver.Conn
+	driver *pingDriver
+	dsn    string
+}
+
+func (c *pingConn) Ping(ctx context.Context) error {
+	n := int(c.driver.pings[c.dsn].Add(1))
+	if errs := c.driver.errs[c.dsn]; n <= len(errs) {
+		// The pool discards a connection that failed to ping.
+		return fmt.Errorf("%w: %w", driver.ErrBadConn, errs[n-1])
+	}
+	return nil
+}
+
+func (c *pingConn) Close() error { return nil }
+
+// pingDatabase registers the ping errors of a new database.
+func pingDatabase(t *testing.T, errs ...error) (Database, *atomic.Int32) {
+	dsn := t.Name()
+	pings := &atomic.Int32{}
+	fakeDriver.errs[dsn] = errs
+	fakeDriver.pings[dsn] = pings
+	return Database{Dialect: Postgres, Driver: "ping", DSN: dsn}, pings
+}
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	opts := OpenOptions{MaxOpenConns: 4, MaxIdleConns: 2, ConnMaxLifetime: time.Minute, ConnectTimeout: 5 * time.Second}
+
+	t.Run("applies the pool", func(t *testing.T) {
+		database, pings := pingDatabase(t)
+		db, err := Open(ctx, database, opts)
+		require.NoError(t, err)
+		defer db.Close()
+		assert.Equal(t, 4, db.Stats().MaxOpenConnections)
+		assert.Equal(t, int32(1), pings.Load())
+	})
+
+	t.Run("retries until the database answers", func(t *testing.T) {
+		refused := errors.New("connection refused")
+		database, pings := pingDatabase(t, refused, refused)
+		start := time.Now()
+		db, err := Open(ctx, database, opts)
+		require.NoError(t, err)
+		defer db.Close()
+		assert.Equal(t, int32(3), pings.Load())
+		// 100ms then 200ms.
+		assert.GreaterOrEqual(t, time.Since(start), 3*pingBackoffMin)
+	})
+
+	t.Run("gives up after the connect timeout", func(t *testing.T) {
+		refused := errors.New("connection refused")
+		database, _ := pingDatabase(t, refused, refused, refused, refused, refused)
+		opts := opts
+		opts.ConnectTimeout = 250 * time.Millisecond
+		_, err := Open(ctx, database, opts)
+		assert.ErrorIs(t, err, refused)
+		assert.ErrorContains(t, err, "no answer within 250ms")
+	})
+
+	t.Run("does not retry invalid credentials", func(t *testing.T) {
+		denied := &pq.Error{Code: "28P01", Message: "password authentication failed"}
+		database, pings := pingDatabase(t, denied, denied)
+		_, err := Open(ctx, database, opts)
+		assert.ErrorIs(t, err, denied)
+		assert.Equal(t, int32(1), pings.Load())
+	})
+
+	t.Run("unknown driver", func(t *testing.T) {
+		_, err := Open(ctx, Database{Dialect: SQLite, Driver: "missing"}, opts)
+		assert.ErrorContains(t, err, `open database: sql: unknown driver "missing"`)
+	})
+
+	t.Run("invalid pool", func(t *testing.T) {
+		database, pings := pingDatabase(t)
+		_, err := Open(ctx, database, OpenOptions{MaxOpenConns: 2, MaxIdleConns: 5, ConnMaxLifetime: -time.Second})
+		assert.EqualError(t, err, "invalid database pool: "+
+			"max idle connections (5) must not exceed max open connections (2); "+
+			"connection max lifetime must not be negative, got -1s; "+
+			"connect timeout must be positive, got 0s")
+		assert.Equal(t, int32(0), pings.Load())
+	})
+
+	t.Run("reloads the DSN of new connections", func(t *testing.T) {
+		var dsn atomic.Value
+		dsn.Store(t.Name() + "/a")
+		db, err := Open(ctx, Database{Dialect: Postgres, Driver: "names", DSN: "unused"}, OpenOptions{
+			ConnectTimeout: time.Second,
+			ReloadDSN:      func() (string, error) { return dsn.Load().(string), nil },
+		})
+		require.NoError(t, err)
+		defer db.Close()
+		// Without idle connections, each query opens one.
+		db.SetMaxIdleConns(0)
+		repo := NewRepository(NewRepositoryOptions{Db: db})
+
+		assert.Equal(t, "a", readFrom(t, ctx, repo))
+		dsn.Store(t.Name() + "/b")
+		assert.Equal(t, "b", readFrom(t, ctx, repo))
+	})
+}
+
+// blockingDB answers statements when their context is done.
+type blockingDB struct {
+	dbtx
+}
+
+func (blockingDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRepository_QueryTimeout(t *testing.T) {
+	t.Parallel()
+
+	repo := &repository{Db: blockingDB{}, dialect: Postgres, queryTimeout: 10 * time.Millisecond}
+	err := repo.UpdatePassword(context.Background(), 1, "hash")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,346 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/lib/jwt"
+	"github.com/SawitProRecruitment/UserService/lib/password"
+	"github.com/SawitProRecruitment/UserService/migrations"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/service"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testDSN is the database the tests run against: TEST_DATABASE_URL when set,
+// otherwise an ephemeral cluster started by TestMain.
+var testDSN string
+
+// TestMain fails when no PostgreSQL can be reached, so the integration tests
+// never pass by skipping every test. SKIP_INTEGRATION skips them instead.
+func TestMain(m *testing.M) {
+	testDSN = os.Getenv("TEST_DATABASE_URL")
+	var stop func()
+	var err error
+	if testDSN == "" {
+		testDSN, stop, err = startPostgres()
+	}
+	if err == nil {
+		err = ping(testDSN)
+	}
+	if err != nil {
+		if stop != nil {
+			stop()
+		}
+		if os.Getenv("SKIP_INTEGRATION") == "" {
+			log.Printf("integration tests need PostgreSQL, set SKIP_INTEGRATION=1 to skip them: %v", err)
+			os.Exit(1)
+		}
+		log.Printf("integration tests skipped: %v", err)
+		testDSN, stop = "", nil
+	}
+	code := m.Run()
+	if stop != nil {
+		stop()
+	}
+	os.Exit(code)
+}
+
+// startPostgres initializes a throwaway cluster in a temporary directory with
+// the initdb and pg_ctl of PG_BIN, or of the PATH. The server only listens on
+// a unix socket in that directory and everything is removed by stop.
+func startPostgres() (dsn string, stop func(), err error) {
+	if os.Geteuid() == 0 {
+		return "", nil, fmt.Errorf("postgres cannot run as root, set TEST_DATABASE_URL instead")
+	}
+	initdb, err := pgBinary("initdb")
+	if err != nil {
+		return "", nil, err
+	}
+	pgCtl, err := pgBinary("pg_ctl")
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "userservice-pg-")
+	if err != nil {
+		return "", nil, err
+	}
+	data := filepath.Join(dir, "data")
+	run := func(name string, args ...string) error {
+		out, err := exec.Command(name, args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s: %w\n%s", filepath.Base(name), err, out)
+		}
+		return nil
+	}
+
+	err = run(initdb, "-D", data, "-U", "postgres", "-A", "trust", "--no-sync", "-E", "UTF8")
+	if err == nil {
+		err = run(pgCtl, "start", "-w", "-D", data, "-l", filepath.Join(dir, "postgres.log"),
+			"-o", fmt.Sprintf("-k %s -c listen_addresses='' -c fsync=off", dir))
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+
+	stop = func() {
+		run(pgCtl, "stop", "-D", data, "-m", "immediate")
+		os.RemoveAll(dir)
+	}
+	return fmt.Sprintf("host=%s user=postgres dbname=postgres sslmode=disable", dir), stop, nil
+}
+
+func ping(dsn string) error {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return db.PingContext(ctx)
+}
+
+func pgBinary(name string) (string, error) {
+	if dir := os.Getenv("PG_BIN"); dir != "" {
+		return filepath.Join(dir, name), nil
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%s not found, set PG_BIN or TEST_DATABASE_URL", name)
+	}
+	return path, nil
+}
+
+type integration struct {
+	ctx  context.Context
+	db   *sql.DB
+	repo repository.RepositoryInterface
+}
+
+// setupIntegration returns a repository over a database with the schema and
+// every migration applied, in a schema of its own that is dropped at the end
+// of the test.
+func setupIntegration(t *testing.T) *integration {
+	if testDSN == "" {
+		t.Skip("SKIP_INTEGRATION is set and there is no PostgreSQL")
+	}
+	ctx := context.Background()
+	admin, err := sql.Open("postgres", testDSN)
+	require.NoError(t, err)
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	_, err = admin.ExecContext(ctx, `CREATE SCHEMA `+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		admin.Close()
+	})
+
+	db, err := sql.Open("postgres", withSearchPath(testDSN, schema))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	ddl, err := os.ReadFile("../database.sql")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, string(ddl))
+	require.NoError(t, err)
+	require.NoError(t, migrations.Up(ctx, db))
+	return &integration{
+		ctx:  ctx,
+		db:   db,
+		repo: repository.NewRepository(repository.NewRepositoryOptions{Db: db}),
+	}
+}
+
+func (s *integration) insertUser(t *testing.T, phone, email string) int64 {
+	id, err := s.repo.InsertUser(s.ctx, repository.User{Name: "rotan", Phone: phone, Password: "hash", Email: email})
+	require.NoError(t, err)
+	return *id
+}
+
+// timestamps returns created_at and updated_at of a row of table.
+func (s *integration) timestamps(t *testing.T, table string, id int64) (createdAt, updatedAt time.Time) {
+	err := s.db.QueryRowContext(s.ctx, `SELECT created_at, updated_at FROM `+table+` WHERE id = $1`, id).
+		Scan(&createdAt, &updatedAt)
+	require.NoError(t, err)
+	return createdAt, updatedAt
+}
+
+// age moves the timestamps of a row one hour back, so that an update is seen.
+func (s *integration) age(t *testing.T, table string, id int64) {
+	_, err := s.db.ExecContext(s.ctx,
+		`UPDATE `+table+` SET created_at = created_at - INTERVAL '1 hour', updated_at = updated_at - INTERVAL '1 hour' WHERE id = $1`, id)
+	require.NoError(t, err)
+}
+
+func (s *integration) assertTouched(t *testing.T, table string, id int64) {
+	createdAt, updatedAt := s.timestamps(t, table, id)
+	assert.WithinDuration(t, time.Now(), updatedAt, time.Minute)
+	assert.True(t, updatedAt.After(createdAt))
+}
+
+// withSearchPath adds the search_path run-time parameter to a lib/pq DSN,
+// either a URL or key=value pairs.
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err == nil {
+			q := u.Query()
+			q.Set("search_path", schema)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return dsn + " search_path=" + schema
+}
+
+func TestRepository_Contract(t *testing.T) {
+	testContract(t, func(t *testing.T) repository.RepositoryInterface {
+		return setupIntegration(t).repo
+	})
+}
+
+func TestRepository_Timestamps(t *testing.T) {
+	s := setupIntegration(t)
+	id := s.insertUser(t, "+6281000000001", "sawit@example.com")
+
+	t.Run("insert user", func(t *testing.T) {
+		createdAt, updatedAt := s.timestamps(t, "users", id)
+		assert.WithinDuration(t, time.Now(), createdAt, time.Minute)
+		assert.Equal(t, createdAt, updatedAt)
+	})
+
+	t.Run("update profile", func(t *testing.T) {
+		s.age(t, "users", id)
+		name := "kebun"
+		_, err := s.repo.UpdateProfile(s.ctx, repository.ProfileUpdate{Id: id, Name: &name})
+		assert.NoError(t, err)
+		s.assertTouched(t, "users", id)
+	})
+
+	t.Run("update avatar", func(t *testing.T) {
+		s.age(t, "users", id)
+		_, err := s.repo.UpdateAvatar(s.ctx, id, "1/avatar")
+		assert.NoError(t, err)
+		s.assertTouched(t, "users", id)
+	})
+
+	t.Run("update password", func(t *testing.T) {
+		s.age(t, "users", id)
+		assert.NoError(t, s.repo.UpdatePassword(s.ctx, id, "rehashed"))
+		s.assertTouched(t, "users", id)
+	})
+
+	t.Run("change password", func(t *testing.T) {
+		s.age(t, "users", id)
+		err := s.repo.ChangePassword(s.ctx, repository.PasswordChangePayload{UserId: id, Password: "changed", HistorySize: 2})
+		assert.NoError(t, err)
+		s.assertTouched(t, "users", id)
+	})
+
+	t.Run("verify email", func(t *testing.T) {
+		err := s.repo.InsertEmailVerification(s.ctx, repository.EmailVerificationPayloadInsert{
+			UserId:    id,
+			Email:     "sawit@example.com",
+			TokenHash: "valid",
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+		s.age(t, "users", id)
+		_, err = s.repo.VerifyEmail(s.ctx, "valid")
+		assert.NoError(t, err)
+		s.assertTouched(t, "users", id)
+	})
+
+	t.Run("upsert token", func(t *testing.T) {
+		token, err := s.repo.UpsertToken(s.ctx, repository.TokenPayloadUpsert{UserId: id, Token: "first"})
+		require.NoError(t, err)
+		createdAt, updatedAt := s.timestamps(t, "user_tokens", token.Id)
+		assert.WithinDuration(t, time.Now(), createdAt, time.Minute)
+		assert.Equal(t, createdAt, updatedAt)
+
+		s.age(t, "user_tokens", token.Id)
+		_, err = s.repo.UpsertToken(s.ctx, repository.TokenPayloadUpsert{UserId: id, Token: "second"})
+		assert.NoError(t, err)
+		s.assertTouched(t, "user_tokens", token.Id)
+	})
+}
+
+func TestInsertUser_ConcurrentPhone(t *testing.T) {
+	s := setupIntegration(t)
+
+	t.Run("service returns a conflict", func(t *testing.T) {
+		svc := service.NewService(service.NewServiceOption{
+			UserRepository: s.repo,
+			PasswordHasher: password.NewBcrypt(password.BcryptParams{Cost: bcrypt.MinCost}),
+		})
+		errs := concurrently(10, func(i int) error {
+			_, err := svc.InsertUser(s.ctx, service.PayloadInsert{
+				Name:     fmt.Sprintf("user %d", i),
+				Phone:    "+6282222222222",
+				Password: "Sawit-Pro-2024",
+			})
+			return err
+		})
+
+		var inserted int
+		for _, err := range errs {
+			if err == nil {
+				inserted++
+				continue
+			}
+			assert.ErrorIs(t, err, service.ErrPhoneAlreadyUsed)
+		}
+		assert.Equal(t, 1, inserted)
+	})
+}
+
+func TestLogin_Concurrent(t *testing.T) {
+	s := setupIntegration(t)
+	svc := service.NewService(service.NewServiceOption{
+		UserRepository: s.repo,
+		TokenService:   jwt.NewTokenService(jwt.Options{PrivateKey: func() string { return "test-key" }}),
+		PasswordHasher: password.NewBcrypt(password.BcryptParams{Cost: bcrypt.MinCost}),
+	})
+	id, err := svc.InsertUser(s.ctx, service.PayloadInsert{
+		Name:     "rotan",
+		Phone:    "+6285555555555",
+		Password: "Sawit-Pro-2024",
+	})
+	require.NoError(t, err)
+
+	errs := concurrently(10, func(int) error {
+		_, err := svc.Login(s.ctx, service.PayloadLogin{Phone: "+6285555555555", Password: "Sawit-Pro-2024"})
+		return err
+	})
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	var rows, count int
+	err = s.db.QueryRowContext(s.ctx, `SELECT COUNT(*), COALESCE(SUM(count_login), 0) FROM user_tokens WHERE user_id = $1`, *id).
+		Scan(&rows, &count)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rows)
+	assert.Equal(t, 10, count)
+
+	token, err := s.repo.UpsertToken(s.ctx, repository.TokenPayloadUpsert{UserId: *id, Token: "token"})
+	require.NoError(t, err)
+	assert.Equal(t, 11, token.CountLogin)
+	assert.Equal(t, "token", token.Token)
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/lib/logger"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// replicas round-robins reads over the replicas that did not fail recently.
+type replicas struct {
+	dbs []*replica
+	// retryAfter is how long a failed replica is skipped.
+	retryAfter time.Duration
+	now        func() time.Time
+	next       atomic.Uint64
+}
+
+type replica struct {
+	index int
+	db    dbtx
+	mu    sync.Mutex
+	// downUntil is when a failed replica is tried again.
+	downUntil time.Time
+}
+
+func newReplicas(dbs []*sql.DB, dialect Dialect, retryAfter time.Duration) *replicas {
+	if len(dbs) == 0 {
+		return nil
+	}
+	rs := &replicas{retryAfter: retryAfter, now: time.Now}
+	for i, db := range dbs {
+		rs.dbs = append(rs.dbs, &replica{index: i, db: dialect.bind(db)})
+	}
+	return rs
+}
+
+// pick returns a healthy replica, or nil when they are all down.
+func (rs *replicas) pick() *replica {
+	start := rs.next.Add(1)
+	now := rs.now()
+	for i := range rs.dbs {
+		r := rs.dbs[(start+uint64(i))%uint64(len(rs.dbs))]
+		if r.up(now) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (r *replica) up(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !now.Before(r.downUntil)
+}
+
+func (r *replica) down(until time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.downUntil = until
+}
+
+// read runs fn on a replica, or on the primary when there is none, when the
+// request already wrote, or when every replica is down. A replica that cannot
+// be reached is skipped for a while and fn is run again on the primary.
+func (r *repository) read(ctx context.Context, fn func(db dbtx) error) error {
+	if r.replicas == nil || wrote(ctx) {
+		return fn(r.Db)
+	}
+	replica := r.replicas.pick()
+	if replica == nil {
+		return fn(r.Db)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("db.replica", replica.index))
+	err := fn(replica.db)
+	if err == nil || !unreachable(err) || ctx.Err() != nil {
+		return err
+	}
+	replica.down(r.replicas.now().Add(r.replicas.retryAfter))
+	logger.FromContext(ctx).Warn("replica unreachable, reading from the primary",
+		slog.Int("replica", replica.index),
+		slog.Duration("retry_after", r.replicas.retryAfter),
+		slog.String("error", err.Error()),
+	)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("db.replica", -1))
+	return fn(r.Db)
+}
+
+// unreachable reports whether err means the database could not serve the
+// query at all, rather than the query failing.
+func unreachable(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Connection exceptions, a shutdown or a server still starting.
+		return pqErr.Code.Class() == "08" ||
+			pqErr.Code == "57P01" || pqErr.Code == "57P02" || pqErr.Code == "57P03"
+	}
+	return false
+}
+
+type writesKey struct{}
+
+// WithReadYourWrites returns a copy of ctx in which every read following a
+// write goes to the primary, so that it sees the write even when replicas lag
+// behind. Requests are given one by middleware.ReadYourWrites.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, writesKey{}, &atomic.Bool{})
+}
+
+// markWrite records a write in ctx, if it tracks them.
+func markWrite(ctx context.Context) {
+	if written, ok := ctx.Value(writesKey{}).(*atomic.Bool); ok {
+		written.Store(true)
+	}
+}
+
+func wrote(ctx context.Context) bool {
+	written, ok := ctx.Value(writesKey{}).(*atomic.Bool)
+	return ok && written.Load()
+}
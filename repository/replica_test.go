@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nameDriver is a database/sql driver answering every user query with a user
+// named after the DSN, or with the error set for the DSN.
+type nameDriver struct {
+	mu   sync.Mutex
+	errs map[string]error
+}
+
+var names = &nameDriver{errs: map[string]error{}}
+
+func init() {
+	sql.Register("names", names)
+}
+
+func (d *nameDriver) fail(dsn string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.errs[dsn] = err
+}
+
+func (d *nameDriver) err(dsn string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.errs[dsn]
+}
+
+func (d *nameDriver) Open(dsn string) (driver.Conn, error) {
+	return &nameConn{dsn: dsn}, nil
+}
+
+type nameConn struct {
+	driver.Conn
+	dsn string
+}
+
+func (c *nameConn) Close() error { return nil }
+
+func (c *nameConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := names.err(c.dsn); err != nil {
+		return nil, err
+	}
+	return &nameRows{values: []driver.Value{int64(1), c.dsn, "+6281000000001", "hash", nil, nil, nil, nil, nil, nil, nil, int64(1)}}, nil
+}
+
+func (c *nameConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := names.err(c.dsn); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type nameRows struct {
+	values []driver.Value
+	read   bool
+}
+
+func (r *nameRows) Columns() []string {
+	return strings.Split(userColumns, ", ")
+}
+
+func (r *nameRows) Close() error { return nil }
+
+func (r *nameRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	copy(dest, r.values)
+	return nil
+}
+
+func openNames(t *testing.T, dsn string) *sql.DB {
+	dsn = t.Name() + "/" + dsn
+	db, err := sql.Open("names", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// readFrom returns the database GetUserById read from.
+func readFrom(t *testing.T, ctx context.Context, repo RepositoryInterface) string {
+	user, err := repo.GetUserById(ctx, 1)
+	require.NoError(t, err)
+	return user.Name[strings.LastIndex(user.Name, "/")+1:]
+}
+
+func TestRepository_Replicas(t *testing.T) {
+	setup := func(t *testing.T, replicas ...string) (*repository, *time.Time) {
+		opts := NewRepositoryOptions{Db: openNames(t, "primary")}
+		for _, replica := range replicas {
+			opts.Replicas = append(opts.Replicas, openNames(t, replica))
+		}
+		repo := NewRepository(opts).(*repository)
+		now := time.Now()
+		if repo.replicas != nil {
+			repo.replicas.now = func() time.Time { return now }
+		}
+		return repo, &now
+	}
+	ctx := context.Background()
+
+	t.Run("without replicas reads from the primary", func(t *testing.T) {
+		repo, _ := setup(t)
+		assert.Equal(t, "primary", readFrom(t, ctx, repo))
+	})
+
+	t.Run("round-robins reads over replicas", func(t *testing.T) {
+		repo, _ := setup(t, "a", "b")
+		read := map[string]int{}
+		for i := 0; i < 4; i++ {
+			read[readFrom(t, ctx, repo)]++
+		}
+		assert.Equal(t, map[string]int{"a": 2, "b": 2}, read)
+	})
+
+	t.Run("reads its writes from the primary", func(t *testing.T) {
+		repo, _ := setup(t, "a")
+		ctx := WithReadYourWrites(ctx)
+		assert.Equal(t, "a", readFrom(t, ctx, repo))
+		require.NoError(t, repo.UpdatePassword(ctx, 1, "rehashed"))
+		assert.Equal(t, "primary", readFrom(t, ctx, repo))
+	})
+
+	t.Run("writes outside a tracked request do not pin reads", func(t *testing.T) {
+		repo, _ := setup(t, "a")
+		require.NoError(t, repo.UpdatePassword(ctx, 1, "rehashed"))
+		assert.Equal(t, "a", readFrom(t, ctx, repo))
+	})
+
+	t.Run("falls back to the primary while a replica is down", func(t *testing.T) {
+		repo, now := setup(t, "a")
+		names.fail(t.Name()+"/a", &pq.Error{Code: "57P03", Message: "the database system is starting up"})
+		assert.Equal(t, "primary", readFrom(t, ctx, repo))
+
+		// The replica is not tried again until it may have recovered.
+		names.fail(t.Name()+"/a", nil)
+		assert.Equal(t, "primary", readFrom(t, ctx, repo))
+		*now = now.Add(30 * time.Second)
+		assert.Equal(t, "a", readFrom(t, ctx, repo))
+	})
+
+	t.Run("skips a replica that is down", func(t *testing.T) {
+		repo, _ := setup(t, "a", "b")
+		names.fail(t.Name()+"/a", fmt.Errorf("dial: %w", driver.ErrBadConn))
+		for i := 0; i < 4; i++ {
+			readFrom(t, ctx, repo)
+		}
+		assert.Equal(t, "b", readFrom(t, ctx, repo))
+		assert.Equal(t, "b", readFrom(t, ctx, repo))
+	})
+
+	t.Run("returns query errors of a replica", func(t *testing.T) {
+		repo, _ := setup(t, "a")
+		names.fail(t.Name()+"/a", &pq.Error{Code: "42P01", Message: "relation does not exist"})
+		_, err := repo.GetUserById(ctx, 1)
+		var pqErr *pq.Error
+		assert.ErrorAs(t, err, &pqErr)
+	})
+}
@@ -2,19 +2,56 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"time"
 )
 
+// dbtx is what *sql.DB and *sql.Tx have in common, so every method runs the
+// same inside and outside a transaction.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type repository struct {
-	Db *sql.DB
+	Db dbtx
+	// db starts transactions, it is nil for a repository bound to one.
+	db *sql.DB
+	// replicas serve reads, they are nil when there are none and for a
+	// repository bound to a transaction.
+	replicas     *replicas
+	dialect      Dialect
+	queryTimeout time.Duration
 }
 
 type NewRepositoryOptions struct {
 	Db *sql.DB
+	// Dialect defaults to Postgres.
+	Dialect Dialect
+	// Replicas serve the Get methods, see WithReadYourWrites.
+	Replicas []*sql.DB
+	// ReplicaRetryAfter is how long a replica that cannot be reached is left
+	// out, defaults to 30s.
+	ReplicaRetryAfter time.Duration
+	// QueryTimeout bounds each method, 0 for no limit. Transactions of
+	// WithTx are only bounded by their context.
+	QueryTimeout time.Duration
 }
 
 func NewRepository(opts NewRepositoryOptions) RepositoryInterface {
+	if opts.Dialect == "" {
+		opts.Dialect = Postgres
+	}
+	if opts.ReplicaRetryAfter == 0 {
+		opts.ReplicaRetryAfter = 30 * time.Second
+	}
 	return &repository{
-		Db: opts.Db,
+		Db:           opts.Dialect.bind(opts.Db),
+		db:           opts.Db,
+		replicas:     newReplicas(opts.Replicas, opts.Dialect, opts.ReplicaRetryAfter),
+		dialect:      opts.Dialect,
+		queryTimeout: opts.QueryTimeout,
 	}
 }
@@ -0,0 +1,25 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/migrations"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLiteRepository(t *testing.T) {
+	testContract(t, func(t *testing.T) repository.RepositoryInterface {
+		database, err := repository.ParseDatabaseURL("sqlite://" + filepath.Join(t.TempDir(), "users.db"))
+		require.NoError(t, err)
+		db, err := sql.Open(database.Driver, database.DSN)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		require.NoError(t, migrations.UpSQLite(context.Background(), db))
+		return repository.NewRepository(repository.NewRepositoryOptions{Db: db, Dialect: database.Dialect})
+	})
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/SawitProRecruitment/UserService/lib/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts a client span describing a single SQL statement and bounds
+// ctx by the query timeout. The returned function ends both, first translating
+// database errors into the errors of this package. Methods defer it with their
+// named error.
+func (r *repository) startSpan(ctx context.Context, operation, query string) (context.Context, func(err *error)) {
+	ctx, span := r.span(ctx, operation, query)
+	cancel := context.CancelFunc(func() {})
+	if r.queryTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, r.queryTimeout)
+	}
+	return ctx, func(err *error) {
+		cancel()
+		endSpan(span, err)
+	}
+}
+
+func (r *repository) span(ctx context.Context, operation, query string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "repository."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			r.dialect.system(),
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(r.dialect.rebind(query)),
+		),
+	)
+}
+
+// endSpan ends a span, first translating database errors into the errors of
+// this package.
+func endSpan(span trace.Span, err *error) {
+	*err = translateError(*err)
+	tracing.End(span, *err)
+}
@@ -0,0 +1,40 @@
+package repository
+
+import "context"
+
+// WithTx runs fn with a repository bound to a new transaction, committed when
+// fn returns nil and rolled back otherwise. Inside fn, WithTx and the methods
+// that need a transaction of their own join the current one.
+func (r *repository) WithTx(ctx context.Context, fn func(repo RepositoryInterface) error) error {
+	return r.inTx(ctx, func(tx *repository) error {
+		return fn(tx)
+	})
+}
+
+func (r *repository) inTx(ctx context.Context, fn func(tx *repository) error) (err error) {
+	markWrite(ctx)
+	if r.db == nil {
+		return fn(r)
+	}
+	// The transaction is not bounded by the query timeout, its statements are.
+	ctx, span := r.span(ctx, "WithTx", "")
+	defer endSpan(span, &err)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	if err = fn(&repository{Db: r.dialect.bind(tx), dialect: r.dialect, queryTimeout: r.queryTimeout}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -1,11 +1,46 @@
 // This file contains types that are used in the repository layer.
 package repository
 
+import (
+	"database/sql"
+	"time"
+)
+
 type User struct {
-	Id       int64
-	Name     string
-	Phone    string
-	Password string
+	Id              int64
+	Name            string
+	Phone           string
+	Password        string
+	Email           string
+	EmailVerifiedAt *time.Time
+	DateOfBirth     *time.Time
+	AvatarURL       string
+	AvatarKey       string
+	Locale          string
+	Timezone        string
+	Version         int64
+}
+
+// ProfileUpdate only writes the columns that are not nil, an invalid Null*
+// value sets the column to NULL. Changing the email resets its verification.
+// When Versions is not empty the user is only updated if its current version
+// is one of them.
+type ProfileUpdate struct {
+	Id          int64
+	Versions    []int64
+	Name        *string
+	Phone       *string
+	Email       *sql.NullString
+	DateOfBirth *sql.NullTime
+	AvatarURL   *sql.NullString
+	Locale      *sql.NullString
+	Timezone    *sql.NullString
+}
+
+// AvatarUpdated is the outcome of replacing the avatar of a user.
+type AvatarUpdated struct {
+	PreviousKey string
+	Version     int64
 }
 
 type UserToken struct {
@@ -15,12 +50,30 @@ type UserToken struct {
 	CountLogin int
 }
 
-type TokenPayloadInsert struct {
+// TokenPayloadUpsert records a login: the token of the user is replaced and
+// its login count incremented, or created on the first login.
+type TokenPayloadUpsert struct {
 	UserId int64
 	Token  string
 }
 
-type TokenPayloadUpdate struct {
-	Id    int64
-	Token string
+// PasswordChangePayload replaces the password of a user, the previous one is
+// kept in the password history which is pruned to HistorySize entries.
+type PasswordChangePayload struct {
+	UserId      int64
+	Password    string
+	HistorySize int
+}
+
+type PasswordResetPayloadInsert struct {
+	UserId    int64
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type EmailVerificationPayloadInsert struct {
+	UserId    int64
+	Email     string
+	TokenHash string
+	ExpiresAt time.Time
 }
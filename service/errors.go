@@ -0,0 +1,63 @@
+package service
+
+import "github.com/SawitProRecruitment/UserService/lib/errors"
+
+const (
+	CodeUserConflict       errors.Code = "USER_CONFLICT"
+	CodeUserNotFound       errors.Code = "USER_NOT_FOUND"
+	CodeInvalidCredentials errors.Code = "INVALID_CREDENTIALS"
+	CodePhoneAlreadyUsed   errors.Code = "PHONE_ALREADY_USED"
+	CodeInvalidPhone       errors.Code = "INVALID_PHONE"
+	CodeInvalidDateOfBirth errors.Code = "INVALID_DATE_OF_BIRTH"
+	CodeEmailAlreadyUsed   errors.Code = "EMAIL_ALREADY_USED"
+	CodeInvalidEmailToken  errors.Code = "INVALID_EMAIL_TOKEN"
+	CodeEmailNotSet        errors.Code = "EMAIL_NOT_SET"
+	CodeEmailVerified      errors.Code = "EMAIL_ALREADY_VERIFIED"
+	CodePasswordTooShort   errors.Code = "PASSWORD_TOO_SHORT"
+	CodePasswordTooLong    errors.Code = "PASSWORD_TOO_LONG"
+	CodePasswordUppercase  errors.Code = "PASSWORD_MISSING_UPPERCASE"
+	CodePasswordLowercase  errors.Code = "PASSWORD_MISSING_LOWERCASE"
+	CodePasswordDigit      errors.Code = "PASSWORD_MISSING_DIGIT"
+	CodePasswordSpecial    errors.Code = "PASSWORD_MISSING_SPECIAL"
+	CodePasswordPersonal   errors.Code = "PASSWORD_CONTAINS_PERSONAL_INFO"
+	CodePasswordBreached   errors.Code = "PASSWORD_BREACHED"
+	CodePasswordReused     errors.Code = "PASSWORD_REUSED"
+	CodePasswordPolicy     errors.Code = "PASSWORD_POLICY"
+	CodeInvalidResetToken  errors.Code = "INVALID_PASSWORD_RESET_TOKEN"
+	CodeFieldRequired      errors.Code = "FIELD_REQUIRED"
+	CodeVersionMismatch    errors.Code = "VERSION_MISMATCH"
+	CodeAvatarTooLarge     errors.Code = "AVATAR_TOO_LARGE"
+	CodeAvatarType         errors.Code = "AVATAR_UNSUPPORTED_TYPE"
+	CodeInvalidAvatar      errors.Code = "INVALID_AVATAR"
+	CodeAvatarNotFound     errors.Code = "AVATAR_NOT_FOUND"
+)
+
+var (
+	ErrConflict             = errors.NewConflictError("conflict with the current state of the user").WithCode(CodeUserConflict)
+	ErrUserNotFound         = errors.NewNotFoundError("user not found").WithCode(CodeUserNotFound)
+	ErrInvalidCredentials   = errors.NewBadRequestError("invalid phone, email or password").WithCode(CodeInvalidCredentials)
+	ErrPhoneAlreadyUsed     = errors.NewConflictError("phone number already used").WithCode(CodePhoneAlreadyUsed)
+	ErrInvalidPhone         = errors.NewBadRequestError("invalid phone number").WithCode(CodeInvalidPhone)
+	ErrInvalidDateOfBirth   = errors.NewBadRequestError("invalid date of birth, want YYYY-MM-DD").WithCode(CodeInvalidDateOfBirth)
+	ErrEmailAlreadyUsed     = errors.NewConflictError("email already used").WithCode(CodeEmailAlreadyUsed)
+	ErrInvalidEmailToken    = errors.NewBadRequestError("invalid or expired email verification token").WithCode(CodeInvalidEmailToken)
+	ErrEmailNotSet          = errors.NewBadRequestError("user has no email").WithCode(CodeEmailNotSet)
+	ErrEmailAlreadyVerified = errors.NewConflictError("email already verified").WithCode(CodeEmailVerified)
+	ErrPasswordTooShort     = errors.NewBadRequestError("password too short").WithCode(CodePasswordTooShort)
+	ErrPasswordTooLong      = errors.NewBadRequestError("password too long").WithCode(CodePasswordTooLong)
+	ErrPasswordUppercase    = errors.NewBadRequestError("password must contain an uppercase letter").WithCode(CodePasswordUppercase)
+	ErrPasswordLowercase    = errors.NewBadRequestError("password must contain a lowercase letter").WithCode(CodePasswordLowercase)
+	ErrPasswordDigit        = errors.NewBadRequestError("password must contain a digit").WithCode(CodePasswordDigit)
+	ErrPasswordSpecial      = errors.NewBadRequestError("password must contain a special character").WithCode(CodePasswordSpecial)
+	ErrPasswordPersonal     = errors.NewBadRequestError("password must not contain your name, phone number or email").WithCode(CodePasswordPersonal)
+	ErrPasswordBreached     = errors.NewBadRequestError("password is too common, it appeared in a data breach").WithCode(CodePasswordBreached)
+	ErrPasswordReused       = errors.NewBadRequestError("password was used recently, choose another one").WithCode(CodePasswordReused)
+	ErrPasswordPolicy       = errors.NewBadRequestError("password does not satisfy the password policy").WithCode(CodePasswordPolicy)
+	ErrInvalidResetToken    = errors.NewBadRequestError("invalid or expired password reset token").WithCode(CodeInvalidResetToken)
+	ErrFieldRequired        = errors.NewBadRequestError("field cannot be removed").WithCode(CodeFieldRequired)
+	ErrVersionMismatch      = errors.NewPreconditionFailedError("user was modified by another request").WithCode(CodeVersionMismatch)
+	ErrAvatarTooLarge       = errors.NewPayloadTooLargeError("avatar too large").WithCode(CodeAvatarTooLarge)
+	ErrAvatarType           = errors.NewUnsupportedMediaTypeError("avatar must be a JPEG, PNG, GIF or WebP image").WithCode(CodeAvatarType)
+	ErrInvalidAvatar        = errors.NewBadRequestError("invalid avatar image").WithCode(CodeInvalidAvatar)
+	ErrAvatarNotFound       = errors.NewNotFoundError("avatar not found").WithCode(CodeAvatarNotFound)
+)
@@ -1,40 +1,79 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
 
+	"github.com/SawitProRecruitment/UserService/lib/avatar"
+	"github.com/SawitProRecruitment/UserService/lib/blob"
 	"github.com/SawitProRecruitment/UserService/lib/errors"
 	"github.com/SawitProRecruitment/UserService/lib/jwt"
+	"github.com/SawitProRecruitment/UserService/lib/logger"
+	"github.com/SawitProRecruitment/UserService/lib/mailer"
+	"github.com/SawitProRecruitment/UserService/lib/password"
+	"github.com/SawitProRecruitment/UserService/lib/tracing"
+	"github.com/SawitProRecruitment/UserService/lib/validator"
 	"github.com/SawitProRecruitment/UserService/repository"
-	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/text/language"
 )
 
-func (s *service) GetByID(ctx context.Context, id int64) (*User, error) {
+func (s *service) GetByID(ctx context.Context, id int64) (_ *User, err error) {
+	ctx, span := tracing.Start(ctx, "service.GetByID")
+	defer func() { tracing.End(span, err) }()
+
 	user, err := s.userRepository.GetUserById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	if user == nil {
-		return nil, errors.NewNotFoundError("user not found")
+		return nil, ErrUserNotFound
 	}
 
-	return ParseUser(user), nil
+	output := ParseUser(user)
+	output.Avatars = s.avatarURLs(user.AvatarKey)
+	return output, nil
 }
 
-func (s *service) Login(ctx context.Context, payload PayloadLogin) (*ResponseLogin, error) {
-	user, err := s.userRepository.GetUserByPhone(ctx, payload.Phone)
+func (s *service) Login(ctx context.Context, payload PayloadLogin) (_ *ResponseLogin, err error) {
+	ctx, span := tracing.Start(ctx, "service.Login")
+	defer func() { tracing.End(span, err) }()
+	// The password hash is not cached.
+	ctx = repository.WithCredentials(ctx)
+
+	user, err := s.getLoginUser(ctx, payload)
 	if err != nil {
 		return nil, err
 	}
 	if user == nil {
-		return nil, errors.NewBadRequestError("invalid phone or password")
+		// There is no user ID to log, and the identifier given is personal data.
+		by := "phone"
+		if payload.Email != "" {
+			by = "email"
+		}
+		logger.FromContext(ctx).Info("login failed: unknown user", slog.String("by", by))
+		return nil, ErrInvalidCredentials
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(payload.Password))
+	err = s.comparePassword(ctx, user.Password, payload.Password)
 	if err != nil {
-		return nil, errors.NewBadRequestError("invalid phone or password")
+		logger.FromContext(ctx).Info("login failed: wrong password", slog.Int64("user_id", user.Id))
+		return nil, ErrInvalidCredentials
+	}
+	if s.passwordHasher.NeedsRehash(user.Password) {
+		s.rehashPassword(ctx, user.Id, payload.Password)
 	}
 
-	token, err := jwt.GenerateToken(jwt.User{
+	token, err := s.tokenService.GenerateToken(jwt.User{
 		ID:    user.Id,
 		Name:  user.Name,
 		Phone: user.Phone,
@@ -43,21 +82,10 @@ func (s *service) Login(ctx context.Context, payload PayloadLogin) (*ResponseLog
 		return nil, err
 	}
 
-	userToken, err := s.userRepository.GetUserToken(ctx, user.Id)
-	if err != nil {
-		return nil, err
-	}
-	if userToken == nil {
-		err = s.userRepository.InsertToken(ctx, repository.TokenPayloadInsert{
-			UserId: user.Id,
-			Token:  *token,
-		})
-	} else {
-		err = s.userRepository.UpdateToken(ctx, repository.TokenPayloadUpdate{
-			Id:    userToken.Id,
-			Token: *token,
-		})
-	}
+	_, err = s.userRepository.UpsertToken(ctx, repository.TokenPayloadUpsert{
+		UserId: user.Id,
+		Token:  *token,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -68,40 +96,599 @@ func (s *service) Login(ctx context.Context, payload PayloadLogin) (*ResponseLog
 	}, nil
 }
 
-func (s *service) UpdateProfile(ctx context.Context, payload PayloadUpdate) error {
-	user, err := s.userRepository.GetUserByPhone(ctx, payload.Phone)
+// getLoginUser looks the user up by email when given, by phone otherwise.
+// Unverified emails cannot be used to login.
+func (s *service) getLoginUser(ctx context.Context, payload PayloadLogin) (*repository.User, error) {
+	if payload.Email != "" {
+		user, err := s.userRepository.GetUserByEmail(ctx, normalizeEmail(payload.Email))
+		if err != nil || user == nil || user.EmailVerifiedAt == nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	phone, err := s.phoneParser.Normalize(payload.Phone)
+	if err != nil {
+		return nil, nil
+	}
+	return s.userRepository.GetUserByPhone(ctx, phone)
+}
+
+// UpdateProfile applies a merge patch, only the supplied fields are written.
+// A new email has to be verified again before it can be used to login. It
+// returns the new version of the user.
+func (s *service) UpdateProfile(ctx context.Context, payload PayloadUpdate) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "service.UpdateProfile")
+	defer func() { tracing.End(span, err) }()
+
+	update := repository.ProfileUpdate{Id: payload.Id, Versions: payload.Versions}
+	if payload.Name.Set {
+		if payload.Name.Null {
+			return 0, fieldRequired("name")
+		}
+		update.Name = &payload.Name.Value
+	}
+	if payload.Phone.Set {
+		if payload.Phone.Null {
+			return 0, fieldRequired("phone")
+		}
+		phone, err := s.phoneParser.Normalize(payload.Phone.Value)
+		if err != nil {
+			return 0, ErrInvalidPhone
+		}
+		user, err := s.userRepository.GetUserByPhone(ctx, phone)
+		if err != nil {
+			return 0, err
+		}
+		if user != nil && user.Id != payload.Id {
+			return 0, ErrPhoneAlreadyUsed
+		}
+		update.Phone = &phone
+	}
+	var newEmail string
+	if payload.Email.Set {
+		email := normalizeEmail(payload.Email.Value)
+		if email != "" {
+			user, err := s.userRepository.GetUserByEmail(ctx, email)
+			if err != nil {
+				return 0, err
+			}
+			if user != nil && user.Id != payload.Id {
+				return 0, ErrEmailAlreadyUsed
+			}
+			if user == nil {
+				newEmail = email
+			}
+		}
+		update.Email = nullString(email)
+	}
+	if payload.DateOfBirth.Set {
+		update.DateOfBirth = &sql.NullTime{}
+		if v, ok := payload.DateOfBirth.Get(); ok {
+			dateOfBirth, err := time.Parse(validator.DateLayout, v)
+			if err != nil {
+				return 0, ErrInvalidDateOfBirth.Wrap(err)
+			}
+			update.DateOfBirth.Time = dateOfBirth
+			update.DateOfBirth.Valid = true
+		}
+	}
+	if payload.AvatarURL.Set {
+		update.AvatarURL = nullString(payload.AvatarURL.Value)
+	}
+	if payload.Locale.Set {
+		locale := payload.Locale.Value
+		if tag, err := language.Parse(locale); err == nil {
+			locale = tag.String()
+		}
+		update.Locale = nullString(locale)
+	}
+	if payload.Timezone.Set {
+		update.Timezone = nullString(payload.Timezone.Value)
+	}
+
+	version, err := s.userRepository.UpdateProfile(ctx, update)
 	if err != nil {
+		return 0, constraintError(err)
+	}
+	if version == nil {
+		if len(payload.Versions) > 0 {
+			return 0, ErrVersionMismatch
+		}
+		return 0, ErrUserNotFound
+	}
+	if newEmail != "" {
+		if err := s.sendEmailVerification(ctx, payload.Id, newEmail); err != nil {
+			logger.FromContext(ctx).Error("failed to send email verification",
+				slog.Int64("user_id", payload.Id),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	return *version, nil
+}
+
+// constraintError maps writes rejected by the database. The lookups done
+// beforehand only give a friendlier error in the common case, concurrent
+// requests can still pass them and are caught by the constraints.
+func constraintError(err error) error {
+	var constraintErr *repository.ConstraintError
+	if !errors.As(err, &constraintErr) {
 		return err
 	}
-	if user != nil && user.Id != payload.Id {
-		return errors.NewConflictError("phone number already used")
+	switch {
+	case constraintErr.Constraint == repository.ConstraintUserPhone:
+		return ErrPhoneAlreadyUsed.Wrap(err)
+	case constraintErr.Constraint == repository.ConstraintUserEmail:
+		return ErrEmailAlreadyUsed.Wrap(err)
+	case constraintErr.Constraint == repository.ConstraintUserPhoneE164:
+		return ErrInvalidPhone.Wrap(err)
+	case errors.Is(err, repository.ErrUniqueViolation), errors.Is(err, repository.ErrForeignKeyViolation):
+		return ErrConflict.Wrap(err)
+	default:
+		return err
 	}
-	return s.userRepository.UpdateProfile(ctx, repository.User{
-		Id:    payload.Id,
-		Name:  payload.Name,
-		Phone: payload.Phone,
-	})
 }
 
-func (s *service) InsertUser(ctx context.Context, payload PayloadInsert) (*int64, error) {
-	user, err := s.userRepository.GetUserByPhone(ctx, payload.Phone)
+func fieldRequired(field string) error {
+	return ErrFieldRequired.WithParams(field+" cannot be removed", map[string]string{"field": field})
+}
+
+// nullString maps "" to NULL, null merge patch members have the zero value.
+func nullString(s string) *sql.NullString {
+	return &sql.NullString{String: s, Valid: s != ""}
+}
+
+func (s *service) InsertUser(ctx context.Context, payload PayloadInsert) (_ *int64, err error) {
+	ctx, span := tracing.Start(ctx, "service.InsertUser")
+	defer func() { tracing.End(span, err) }()
+
+	phone, err := s.phoneParser.Normalize(payload.Phone)
+	if err != nil {
+		return nil, ErrInvalidPhone
+	}
+	user, err := s.userRepository.GetUserByPhone(ctx, phone)
 	if err != nil {
 		return nil, err
 	}
 	if user != nil {
-		return nil, errors.NewConflictError("phone number already used")
+		return nil, ErrPhoneAlreadyUsed
+	}
+	email := normalizeEmail(payload.Email)
+	if email != "" {
+		user, err = s.userRepository.GetUserByEmail(ctx, email)
+		if err != nil {
+			return nil, err
+		}
+		if user != nil {
+			return nil, ErrEmailAlreadyUsed
+		}
+	}
+	err = s.checkPasswordPolicy(payload.Password, password.PersonalInfo{
+		Name:  payload.Name,
+		Phone: phone,
+		Email: email,
+	})
+	if err != nil {
+		return nil, err
 	}
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hashPassword(ctx, payload.Password)
 	if err != nil {
 		return nil, err
 	}
 	id, err := s.userRepository.InsertUser(ctx, repository.User{
 		Name:     payload.Name,
-		Phone:    payload.Phone,
-		Password: string(hashedPassword),
+		Phone:    phone,
+		Email:    email,
+		Password: hashedPassword,
 	})
 	if err != nil {
-		return nil, err
+		return nil, constraintError(err)
+	}
+	if email != "" {
+		// The account exists already, the user can ask for a new link.
+		if err := s.sendEmailVerification(ctx, *id, email); err != nil {
+			logger.FromContext(ctx).Error("failed to send email verification",
+				slog.Int64("user_id", *id),
+				slog.String("error", err.Error()),
+			)
+		}
 	}
 	return id, nil
 }
+
+func (s *service) VerifyEmail(ctx context.Context, payload PayloadVerifyEmail) (err error) {
+	ctx, span := tracing.Start(ctx, "service.VerifyEmail")
+	defer func() { tracing.End(span, err) }()
+
+	id, err := s.userRepository.VerifyEmail(ctx, hashToken(payload.Token))
+	if err != nil {
+		return err
+	}
+	if id == nil {
+		return ErrInvalidEmailToken
+	}
+	return nil
+}
+
+func (s *service) ResendEmailVerification(ctx context.Context, id int64) (err error) {
+	ctx, span := tracing.Start(ctx, "service.ResendEmailVerification")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepository.GetUserById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if user.Email == "" {
+		return ErrEmailNotSet
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+	return s.sendEmailVerification(ctx, user.Id, user.Email)
+}
+
+func (s *service) ChangePassword(ctx context.Context, payload PayloadChangePassword) (err error) {
+	ctx, span := tracing.Start(ctx, "service.ChangePassword")
+	defer func() { tracing.End(span, err) }()
+	// The password hash is not cached.
+	ctx = repository.WithCredentials(ctx)
+
+	user, err := s.userRepository.GetUserById(ctx, payload.Id)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if err := s.comparePassword(ctx, user.Password, payload.CurrentPassword); err != nil {
+		return ErrInvalidCredentials
+	}
+	return s.setPassword(ctx, user, payload.NewPassword, nil)
+}
+
+// RequestPasswordReset mails a reset link to a verified email. Unknown or
+// unverified emails are ignored silently, so the endpoint cannot be used to
+// find out which emails are registered.
+func (s *service) RequestPasswordReset(ctx context.Context, payload PayloadRequestPasswordReset) (err error) {
+	ctx, span := tracing.Start(ctx, "service.RequestPasswordReset")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepository.GetUserByEmail(ctx, normalizeEmail(payload.Email))
+	if err != nil || user == nil || user.EmailVerifiedAt == nil {
+		return err
+	}
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	err = s.userRepository.InsertPasswordReset(ctx, repository.PasswordResetPayloadInsert{
+		UserId:    user.Id,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := s.passwordResetURL + "?" + url.Values{"token": {token}}.Encode()
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    "Open the following link to reset your password, it expires in 1 hour:\n\n" + link,
+	})
+}
+
+func (s *service) ResetPassword(ctx context.Context, payload PayloadResetPassword) (err error) {
+	ctx, span := tracing.Start(ctx, "service.ResetPassword")
+	defer func() { tracing.End(span, err) }()
+	// The password hash is not cached.
+	ctx = repository.WithCredentials(ctx)
+
+	tokenHash := hashToken(payload.Token)
+	id, err := s.userRepository.GetPasswordReset(ctx, tokenHash)
+	if err != nil {
+		return err
+	}
+	if id == nil {
+		return ErrInvalidResetToken
+	}
+	user, err := s.userRepository.GetUserById(ctx, *id)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrInvalidResetToken
+	}
+	// The token is only consumed once the new password is accepted, so a
+	// rejected password can be corrected with the same link.
+	return s.setPassword(ctx, user, payload.Password, func(repo repository.RepositoryInterface) error {
+		id, err := repo.UsePasswordReset(ctx, tokenHash)
+		if err == nil && id == nil {
+			return ErrInvalidResetToken
+		}
+		return err
+	})
+}
+
+// UploadAvatar stores the thumbnails of a new avatar and then removes the ones
+// of the previous avatar. Every upload gets new keys, so the blobs never change
+// and can be cached forever.
+func (s *service) UploadAvatar(ctx context.Context, payload PayloadUploadAvatar) (_ *ResponseUploadAvatar, err error) {
+	ctx, span := tracing.Start(ctx, "service.UploadAvatar")
+	defer func() { tracing.End(span, err) }()
+
+	if s.avatarStore == nil {
+		return nil, errors.NewInternalError(fmt.Errorf("no avatar store configured"))
+	}
+	data, err := io.ReadAll(io.LimitReader(payload.File, s.avatarMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.avatarMaxSize {
+		return nil, AvatarTooLarge(s.avatarMaxSize)
+	}
+	thumbnails, err := avatar.Process(data)
+	switch {
+	case errors.Is(err, avatar.ErrUnsupportedType):
+		return nil, ErrAvatarType
+	case errors.Is(err, avatar.ErrInvalidImage), errors.Is(err, avatar.ErrTooManyPixels):
+		return nil, ErrInvalidAvatar.Wrap(err)
+	case err != nil:
+		return nil, err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%d/%s", payload.Id, token)
+	for _, thumbnail := range thumbnails {
+		err = s.avatarStore.Put(ctx, avatarBlobKey(key, thumbnail.Size), bytes.NewReader(thumbnail.Data), avatar.ContentType)
+		if err != nil {
+			s.deleteAvatar(ctx, key)
+			return nil, err
+		}
+	}
+	updated, err := s.userRepository.UpdateAvatar(ctx, payload.Id, key)
+	if err == nil && updated == nil {
+		err = ErrUserNotFound
+	}
+	if err != nil {
+		s.deleteAvatar(ctx, key)
+		return nil, err
+	}
+	if updated.PreviousKey != "" {
+		s.deleteAvatar(ctx, updated.PreviousKey)
+	}
+	return &ResponseUploadAvatar{
+		Version: updated.Version,
+		Avatars: s.avatarURLs(key),
+	}, nil
+}
+
+// GetAvatar returns a thumbnail stored by UploadAvatar, file being the last
+// element of its key.
+func (s *service) GetAvatar(ctx context.Context, userId int64, file string) (_ *blob.Object, err error) {
+	ctx, span := tracing.Start(ctx, "service.GetAvatar")
+	defer func() { tracing.End(span, err) }()
+
+	if s.avatarStore == nil || strings.Contains(file, "/") {
+		return nil, ErrAvatarNotFound
+	}
+	object, err := s.avatarStore.Get(ctx, fmt.Sprintf("%d/%s", userId, file))
+	if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+		return nil, ErrAvatarNotFound
+	}
+	return object, err
+}
+
+func avatarBlobKey(key string, size avatar.Size) string {
+	return key + "-" + size.Name + ".jpg"
+}
+
+func (s *service) avatarURLs(key string) map[string]string {
+	if key == "" || s.avatarStore == nil {
+		return nil
+	}
+	urls := make(map[string]string, len(avatar.Sizes))
+	for _, size := range avatar.Sizes {
+		urls[size.Name] = s.avatarStore.URL(avatarBlobKey(key, size))
+	}
+	return urls
+}
+
+// deleteAvatar removes the thumbnails of an avatar. A blob left behind only
+// wastes space, so failures are logged and not returned.
+func (s *service) deleteAvatar(ctx context.Context, key string) {
+	for _, size := range avatar.Sizes {
+		if err := s.avatarStore.Delete(ctx, avatarBlobKey(key, size)); err != nil {
+			logger.FromContext(ctx).Error("failed to delete avatar",
+				slog.String("key", avatarBlobKey(key, size)),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+// AvatarTooLarge is ErrAvatarTooLarge with max, the limit in bytes, in its
+// message.
+func AvatarTooLarge(max int64) error {
+	size := fmt.Sprintf("%d KiB", max>>10)
+	if max >= 1<<20 && max%(1<<20) == 0 {
+		size = fmt.Sprintf("%d MiB", max>>20)
+	}
+	return ErrAvatarTooLarge.WithParams("avatar must be at most "+size, map[string]string{"max": size})
+}
+
+// setPassword checks the new password against the policy and the password
+// history before storing it. beforeStore, if any, runs right before the
+// password is stored, in the same transaction, and aborts the change when it
+// fails.
+func (s *service) setPassword(ctx context.Context, user *repository.User, plain string, beforeStore func(repo repository.RepositoryInterface) error) error {
+	err := s.checkPasswordPolicy(plain, password.PersonalInfo{
+		Name:  user.Name,
+		Phone: user.Phone,
+		Email: user.Email,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.checkPasswordReuse(ctx, user, plain); err != nil {
+		return err
+	}
+	hashedPassword, err := s.hashPassword(ctx, plain)
+	if err != nil {
+		return err
+	}
+	return s.userRepository.WithTx(ctx, func(repo repository.RepositoryInterface) error {
+		if beforeStore != nil {
+			if err := beforeStore(repo); err != nil {
+				return err
+			}
+		}
+		return repo.ChangePassword(ctx, repository.PasswordChangePayload{
+			UserId:      user.Id,
+			Password:    hashedPassword,
+			HistorySize: s.passwordHistorySize,
+		})
+	})
+}
+
+// checkPasswordReuse rejects the current password and the ones in the
+// history, compared with the hasher since every hash has its own salt.
+func (s *service) checkPasswordReuse(ctx context.Context, user *repository.User, plain string) error {
+	hashes := []string{user.Password}
+	if s.passwordHistorySize > 0 {
+		history, err := s.userRepository.GetPasswordHistory(ctx, user.Id, s.passwordHistorySize)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, history...)
+	}
+	for _, hash := range hashes {
+		err := s.comparePassword(ctx, hash, plain)
+		if err == nil {
+			return ErrPasswordReused
+		}
+		if !errors.Is(err, password.ErrMismatch) && !errors.Is(err, password.ErrUnknownHash) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *service) sendEmailVerification(ctx context.Context, userId int64, email string) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	err = s.userRepository.InsertEmailVerification(ctx, repository.EmailVerificationPayloadInsert{
+		UserId:    userId,
+		Email:     email,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(emailVerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := s.emailVerificationURL + "?" + url.Values{"token": {token}}.Encode()
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Verify your email",
+		Body:    "Open the following link to verify your email, it expires in 24 hours:\n\n" + link,
+	})
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// newToken returns a random, URL safe one-time token.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is what gets stored, so a database leak does not leak usable tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkPasswordPolicy is only for new passwords, login never checks the policy
+// so tightening it does not lock out existing users.
+func (s *service) checkPasswordPolicy(plain string, info password.PersonalInfo) error {
+	return policyError(s.passwordPolicy.Check(plain, info))
+}
+
+// policyError returns the service error of the rule a password.PolicyError
+// reports. A rule without one of its own gets the generic ErrPasswordPolicy.
+func policyError(err error) error {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return err
+	}
+	switch policyErr.Rule {
+	case password.RuleMinLength:
+		return ErrPasswordTooShort.WithParams(
+			"password must be at least "+policyErr.Param+" characters",
+			map[string]string{"min": policyErr.Param},
+		)
+	case password.RuleMaxLength:
+		return ErrPasswordTooLong
+	case password.RuleUppercase:
+		return ErrPasswordUppercase
+	case password.RuleLowercase:
+		return ErrPasswordLowercase
+	case password.RuleDigit:
+		return ErrPasswordDigit
+	case password.RuleSpecial:
+		return ErrPasswordSpecial
+	case password.RulePersonalInfo:
+		return ErrPasswordPersonal
+	case password.RuleBreached:
+		return ErrPasswordBreached
+	default:
+		return ErrPasswordPolicy.Wrap(err)
+	}
+}
+
+func (s *service) hashPassword(ctx context.Context, plain string) (_ string, err error) {
+	_, span := tracing.Start(ctx, "password.Hash")
+	defer func() { tracing.End(span, err) }()
+
+	hash, err := s.passwordHasher.Hash(plain)
+	if errors.Is(err, password.ErrTooLong) {
+		return "", ErrPasswordTooLong
+	}
+	return hash, err
+}
+
+func (s *service) comparePassword(ctx context.Context, hashedPassword, plain string) (err error) {
+	_, span := tracing.Start(ctx, "password.Compare")
+	defer func() { tracing.End(span, err) }()
+
+	return s.passwordHasher.Compare(hashedPassword, plain)
+}
+
+// rehashPassword upgrades a hash made with an outdated algorithm or parameters
+// while the plain password is known. Failing to do so does not fail the login.
+func (s *service) rehashPassword(ctx context.Context, userId int64, plain string) {
+	hash, err := s.hashPassword(ctx, plain)
+	if err == nil {
+		err = s.userRepository.UpdatePassword(ctx, userId, hash)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to rehash password",
+			slog.Int64("user_id", userId),
+			slog.String("error", err.Error()),
+		)
+	}
+}
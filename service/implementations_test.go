@@ -1,41 +1,89 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
 	"fmt"
+	"image"
+	"image/png"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/SawitProRecruitment/UserService/lib/blob"
+	"github.com/SawitProRecruitment/UserService/lib/cache"
 	"github.com/SawitProRecruitment/UserService/lib/errors"
+	"github.com/SawitProRecruitment/UserService/lib/jwt"
+	"github.com/SawitProRecruitment/UserService/lib/mailer"
+	"github.com/SawitProRecruitment/UserService/lib/password"
+	"github.com/SawitProRecruitment/UserService/lib/patch"
 	"github.com/SawitProRecruitment/UserService/repository"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 type component struct {
-	ctx        context.Context
-	repository *repository.MockRepositoryInterface
-	service    ServiceInterface
-	mockedErr  error
+	ctx         context.Context
+	repository  *repository.MockRepositoryInterface
+	mailer      *mailer.MockMailer
+	avatarStore *blob.MockBlobStore
+	hasher      password.PasswordHasher
+	tokens      jwt.TokenService
+	service     ServiceInterface
+	mockedErr   error
 }
 
 func setupService(t *testing.T) *component {
 	g := gomock.NewController(t)
 
 	repository := repository.NewMockRepositoryInterface(g)
+	mailer := mailer.NewMockMailer(g)
+	avatarStore := blob.NewMockBlobStore(g)
+	hasher := password.NewHasher(password.DefaultOptions)
+	tokens := jwt.NewTokenService(jwt.Options{
+		PrivateKey: func() string { return "test-key" },
+		Issuer:     "test",
+	})
 	service := NewService(NewServiceOption{
-		UserRepository: repository,
+		UserRepository:       repository,
+		TokenService:         tokens,
+		PasswordHasher:       hasher,
+		Mailer:               mailer,
+		EmailVerificationURL: "http://localhost/verify-email",
+		PasswordResetURL:     "http://localhost/reset-password",
+		PasswordHistorySize:  2,
+		AvatarStore:          avatarStore,
 	})
+	runTxOnMock(repository)
 
 	return &component{
-		ctx:        context.Background(),
-		repository: repository,
-		service:    service,
-		mockedErr:  fmt.Errorf("mocked error"),
+		ctx:         context.Background(),
+		repository:  repository,
+		mailer:      mailer,
+		avatarStore: avatarStore,
+		hasher:      hasher,
+		tokens:      tokens,
+		service:     service,
+		mockedErr:   fmt.Errorf("mocked error"),
 	}
 }
 
+// runTxOnMock makes WithTx run its callback against the mock itself.
+func runTxOnMock(m *repository.MockRepositoryInterface) {
+	m.EXPECT().WithTx(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(_ context.Context, fn func(repository.RepositoryInterface) error) error {
+			return fn(m)
+		})
+}
+
 func TestUserService_GetByID(t *testing.T) {
 	t.Parallel()
 
@@ -54,7 +102,7 @@ func TestUserService_GetByID(t *testing.T) {
 
 		result, err := s.service.GetByID(s.ctx, 1)
 		assert.Nil(t, result)
-		assert.Equal(t, errors.NewNotFoundError("user not found"), err)
+		assert.ErrorIs(t, err, ErrUserNotFound)
 	})
 
 	t.Run("successfully get user", func(t *testing.T) {
@@ -75,6 +123,18 @@ func TestUserService_GetByID(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, expected, result)
 	})
+
+	t.Run("avatar urls", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetUserById(gomock.Any(), gomock.Any()).Return(&repository.User{Id: 1, AvatarKey: "1/abc"}, nil)
+		s.avatarStore.EXPECT().URL(gomock.Any()).Times(3).DoAndReturn(func(key string) string {
+			return "http://localhost/v1/avatars/" + key
+		})
+
+		result, err := s.service.GetByID(s.ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "http://localhost/v1/avatars/1/abc-medium.jpg", result.Avatars["medium"])
+	})
 }
 
 func TestUserService_Login(t *testing.T) {
@@ -101,7 +161,7 @@ func TestUserService_Login(t *testing.T) {
 			Password: "password",
 		})
 		assert.Nil(t, result)
-		assert.Equal(t, errors.NewBadRequestError("invalid phone or password"), err)
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
 	})
 
 	t.Run("invalid password", func(t *testing.T) {
@@ -119,22 +179,21 @@ func TestUserService_Login(t *testing.T) {
 			Password: "wrong_password",
 		})
 		assert.Nil(t, result)
-		assert.Equal(t, errors.NewBadRequestError("invalid phone or password"), err)
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
 	})
 
-	t.Run("error inserting token", func(t *testing.T) {
+	t.Run("error upserting token", func(t *testing.T) {
 		s := setupService(t)
 		password := "password"
-		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		hashedPassword, _ := s.hasher.Hash(password)
 		user := &repository.User{
 			Id:       1,
 			Name:     "rotan",
 			Phone:    "+628123456789",
-			Password: string(hashedPassword),
+			Password: hashedPassword,
 		}
 		s.repository.EXPECT().GetUserByPhone(gomock.Any(), gomock.Any()).Return(user, nil)
-		s.repository.EXPECT().GetUserToken(gomock.Any(), gomock.Any()).Return(nil, nil)
-		s.repository.EXPECT().InsertToken(gomock.Any(), gomock.Any()).Return(s.mockedErr)
+		s.repository.EXPECT().UpsertToken(gomock.Any(), gomock.Any()).Return(nil, s.mockedErr)
 
 		result, err := s.service.Login(s.ctx, PayloadLogin{
 			Phone:    "+628123456789",
@@ -144,32 +203,39 @@ func TestUserService_Login(t *testing.T) {
 		assert.Equal(t, s.mockedErr, err)
 	})
 
-	t.Run("error update token", func(t *testing.T) {
+	t.Run("successfully login", func(t *testing.T) {
 		s := setupService(t)
 		password := "password"
-		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		hashedPassword, _ := s.hasher.Hash(password)
 		user := &repository.User{
 			Id:       1,
 			Name:     "rotan",
 			Phone:    "+628123456789",
-			Password: string(hashedPassword),
+			Password: hashedPassword,
 		}
 		s.repository.EXPECT().GetUserByPhone(gomock.Any(), gomock.Any()).Return(user, nil)
-		s.repository.EXPECT().GetUserToken(gomock.Any(), gomock.Any()).Return(&repository.UserToken{}, nil)
-		s.repository.EXPECT().UpdateToken(gomock.Any(), gomock.Any()).Return(s.mockedErr)
+		s.repository.EXPECT().UpsertToken(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, payload repository.TokenPayloadUpsert) (*repository.UserToken, error) {
+				assert.Equal(t, int64(1), payload.UserId)
+				assert.NotEmpty(t, payload.Token)
+				return &repository.UserToken{Id: 1, UserId: 1, Token: payload.Token, CountLogin: 1}, nil
+			})
 
 		result, err := s.service.Login(s.ctx, PayloadLogin{
 			Phone:    "+628123456789",
 			Password: password,
 		})
-		assert.Nil(t, result)
-		assert.Equal(t, s.mockedErr, err)
+		assert.NoError(t, err)
+		assert.NotNil(t, result)
+		tokenUser, err := s.tokens.GetDataFromToken(result.Token)
+		assert.NoError(t, err)
+		assert.Equal(t, &jwt.User{ID: 1, Name: "rotan", Phone: "+628123456789"}, tokenUser)
 	})
 
-	t.Run("successfully login", func(t *testing.T) {
+	t.Run("rehashes outdated password hash", func(t *testing.T) {
 		s := setupService(t)
 		password := "password"
-		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
 		user := &repository.User{
 			Id:       1,
 			Name:     "rotan",
@@ -177,8 +243,33 @@ func TestUserService_Login(t *testing.T) {
 			Password: string(hashedPassword),
 		}
 		s.repository.EXPECT().GetUserByPhone(gomock.Any(), gomock.Any()).Return(user, nil)
-		s.repository.EXPECT().GetUserToken(gomock.Any(), gomock.Any()).Return(nil, nil)
-		s.repository.EXPECT().InsertToken(gomock.Any(), gomock.Any()).Return(nil)
+		s.repository.EXPECT().UpdatePassword(gomock.Any(), int64(1), gomock.Any()).DoAndReturn(func(_ context.Context, _ int64, hash string) error {
+			assert.True(t, strings.HasPrefix(hash, "$argon2id$"))
+			assert.NoError(t, s.hasher.Compare(hash, password))
+			return nil
+		})
+		s.repository.EXPECT().UpsertToken(gomock.Any(), gomock.Any()).Return(&repository.UserToken{}, nil)
+
+		result, err := s.service.Login(s.ctx, PayloadLogin{
+			Phone:    "+628123456789",
+			Password: password,
+		})
+		assert.NoError(t, err)
+		assert.NotNil(t, result)
+	})
+
+	t.Run("failing to rehash does not fail login", func(t *testing.T) {
+		s := setupService(t)
+		password := "password"
+		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		user := &repository.User{
+			Id:       1,
+			Phone:    "+628123456789",
+			Password: string(hashedPassword),
+		}
+		s.repository.EXPECT().GetUserByPhone(gomock.Any(), gomock.Any()).Return(user, nil)
+		s.repository.EXPECT().UpdatePassword(gomock.Any(), int64(1), gomock.Any()).Return(s.mockedErr)
+		s.repository.EXPECT().UpsertToken(gomock.Any(), gomock.Any()).Return(&repository.UserToken{}, nil)
 
 		result, err := s.service.Login(s.ctx, PayloadLogin{
 			Phone:    "+628123456789",
@@ -187,23 +278,109 @@ func TestUserService_Login(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
 	})
+
+	t.Run("successfully login with verified email", func(t *testing.T) {
+		s := setupService(t)
+		password := "password"
+		hashedPassword, _ := s.hasher.Hash(password)
+		verifiedAt := time.Now()
+		user := &repository.User{
+			Id:              1,
+			Name:            "rotan",
+			Phone:           "+628123456789",
+			Email:           "rotan@example.com",
+			EmailVerifiedAt: &verifiedAt,
+			Password:        hashedPassword,
+		}
+		s.repository.EXPECT().GetUserByEmail(gomock.Any(), "rotan@example.com").Return(user, nil)
+		s.repository.EXPECT().UpsertToken(gomock.Any(), gomock.Any()).Return(&repository.UserToken{}, nil)
+
+		result, err := s.service.Login(s.ctx, PayloadLogin{
+			Email:    " Rotan@Example.com",
+			Password: password,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), result.UserId)
+	})
+
+	t.Run("unverified email cannot login", func(t *testing.T) {
+		s := setupService(t)
+		hashedPassword, _ := s.hasher.Hash("password")
+		user := &repository.User{
+			Id:       1,
+			Email:    "rotan@example.com",
+			Password: hashedPassword,
+		}
+		s.repository.EXPECT().GetUserByEmail(gomock.Any(), "rotan@example.com").Return(user, nil)
+
+		result, err := s.service.Login(s.ctx, PayloadLogin{
+			Email:    "rotan@example.com",
+			Password: "password",
+		})
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
+
+func TestUserService_LoginTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	t.Run("login records service and password spans", func(t *testing.T) {
+		s := setupService(t)
+		password := "password"
+		hashedPassword, _ := s.hasher.Hash(password)
+		user := &repository.User{
+			Id:       1,
+			Name:     "rotan",
+			Phone:    "+628123456789",
+			Password: hashedPassword,
+		}
+		s.repository.EXPECT().GetUserByPhone(gomock.Any(), gomock.Any()).Return(user, nil)
+		s.repository.EXPECT().UpsertToken(gomock.Any(), gomock.Any()).Return(&repository.UserToken{}, nil)
+
+		_, err := s.service.Login(s.ctx, PayloadLogin{
+			Phone:    "+628123456789",
+			Password: password,
+		})
+		assert.NoError(t, err)
+
+		spans := exporter.GetSpans()
+		assert.Len(t, spans, 2)
+		assert.Equal(t, "password.Compare", spans[0].Name)
+		assert.Equal(t, "service.Login", spans[1].Name)
+		assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+	})
 }
 
 func TestUserService_UpdateProfile(t *testing.T) {
 	t.Parallel()
+	version := int64(2)
 
 	t.Run("error getting user by phone", func(t *testing.T) {
 		s := setupService(t)
 		s.repository.EXPECT().GetUserByPhone(gomock.Any(), gomock.Any()).Return(nil, s.mockedErr)
 
-		err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+		_, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
 			Id:    1,
-			Name:  "rotan",
-			Phone: "+628123456789",
+			Name:  patch.Value("rotan"),
+			Phone: patch.Value("+628123456789"),
 		})
 		assert.Equal(t, s.mockedErr, err)
 	})
 
+	t.Run("invalid date of birth", func(t *testing.T) {
+		s := setupService(t)
+
+		_, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+			Id:          1,
+			DateOfBirth: patch.Value(""),
+		})
+		assert.ErrorIs(t, err, ErrInvalidDateOfBirth)
+	})
+
 	t.Run("phone number already used", func(t *testing.T) {
 		s := setupService(t)
 		user := &repository.User{
@@ -213,23 +390,179 @@ func TestUserService_UpdateProfile(t *testing.T) {
 		}
 		s.repository.EXPECT().GetUserByPhone(gomock.Any(), gomock.Any()).Return(user, nil)
 
-		err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+		_, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
 			Id:    1,
-			Name:  "rotan",
-			Phone: "+628123456789",
+			Name:  patch.Value("rotan"),
+			Phone: patch.Value("+628123456789"),
 		})
-		assert.Equal(t, errors.NewConflictError("phone number already used"), err)
+		assert.ErrorIs(t, err, ErrPhoneAlreadyUsed)
 	})
 
 	t.Run("successfully update profile", func(t *testing.T) {
 		s := setupService(t)
 		s.repository.EXPECT().GetUserByPhone(gomock.Any(), gomock.Any()).Return(nil, nil)
-		s.repository.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(nil)
+		s.repository.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(&version, nil)
 
-		err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+		result, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
 			Id:    1,
-			Name:  "rotan",
-			Phone: "+628123456789",
+			Name:  patch.Value("rotan"),
+			Phone: patch.Value("+628123456789"),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, version, result)
+	})
+
+	t.Run("update is conditional on the versions", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, update repository.ProfileUpdate) (*int64, error) {
+			assert.Equal(t, []int64{1}, update.Versions)
+			return &version, nil
+		})
+
+		result, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+			Id:       1,
+			Versions: []int64{1},
+			Name:     patch.Value("rotan"),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, version, result)
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+		_, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+			Id:       1,
+			Versions: []int64{1},
+			Name:     patch.Value("rotan"),
+		})
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+		_, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+			Id:   1,
+			Name: patch.Value("rotan"),
+		})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("only writes supplied fields", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, update repository.ProfileUpdate) (*int64, error) {
+			locale := sql.NullString{String: "id-ID", Valid: true}
+			assert.Equal(t, repository.ProfileUpdate{
+				Id:          1,
+				Locale:      &locale,
+				DateOfBirth: &sql.NullTime{},
+			}, update)
+			return &version, nil
+		})
+
+		_, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+			Id:          1,
+			Locale:      patch.Value("id-id"),
+			DateOfBirth: patch.Null[string](),
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("required fields cannot be removed", func(t *testing.T) {
+		s := setupService(t)
+
+		_, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+			Id:   1,
+			Name: patch.Null[string](),
+		})
+		var appErr *errors.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, CodeFieldRequired, appErr.Code)
+		assert.Equal(t, map[string]string{"field": "name"}, appErr.Params)
+	})
+
+	t.Run("email already used", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetUserByEmail(gomock.Any(), "rotan@example.com").Return(&repository.User{Id: 2}, nil)
+
+		_, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+			Id:    1,
+			Email: patch.Value("rotan@example.com"),
+		})
+		assert.ErrorIs(t, err, ErrEmailAlreadyUsed)
+	})
+
+	t.Run("new email is verified again", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetUserByEmail(gomock.Any(), "rotan@example.com").Return(nil, nil)
+		s.repository.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, update repository.ProfileUpdate) (*int64, error) {
+			assert.Equal(t, &sql.NullString{String: "rotan@example.com", Valid: true}, update.Email)
+			return &version, nil
+		})
+		s.repository.EXPECT().InsertEmailVerification(gomock.Any(), gomock.Any()).Return(nil)
+		s.mailer.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil)
+
+		_, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+			Id:    1,
+			Email: patch.Value("Rotan@example.com"),
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("same email is not verified again", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetUserByEmail(gomock.Any(), "rotan@example.com").Return(&repository.User{Id: 1}, nil)
+		s.repository.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(&version, nil)
+
+		_, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+			Id:    1,
+			Email: patch.Value("rotan@example.com"),
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("email taken concurrently", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetUserByEmail(gomock.Any(), "rotan@example.com").Return(nil, nil)
+		s.repository.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(nil, &repository.ConstraintError{
+			Kind:       repository.ErrUniqueViolation,
+			Constraint: repository.ConstraintUserEmail,
+		})
+
+		_, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+			Id:    1,
+			Email: patch.Value("rotan@example.com"),
+		})
+		assert.ErrorIs(t, err, ErrEmailAlreadyUsed)
+	})
+
+	t.Run("other constraints conflict", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(nil, &repository.ConstraintError{
+			Kind:       repository.ErrForeignKeyViolation,
+			Constraint: "users_fkey",
+		})
+
+		_, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+			Id:   1,
+			Name: patch.Value("rotan"),
+		})
+		assert.ErrorIs(t, err, ErrConflict)
+	})
+
+	t.Run("null removes email", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, update repository.ProfileUpdate) (*int64, error) {
+			assert.Equal(t, &sql.NullString{}, update.Email)
+			return &version, nil
+		})
+
+		_, err := s.service.UpdateProfile(s.ctx, PayloadUpdate{
+			Id:    1,
+			Email: patch.Null[string](),
 		})
 		assert.NoError(t, err)
 	})
@@ -244,7 +577,7 @@ func TestUserService_InsertUser(t *testing.T) {
 		result, err := s.service.InsertUser(s.ctx, PayloadInsert{
 			Name:     "rotan",
 			Phone:    "+628123456789",
-			Password: "password",
+			Password: "Sawit-Pro-2024",
 		})
 		assert.Nil(t, result)
 		assert.Equal(t, s.mockedErr, err)
@@ -262,10 +595,67 @@ func TestUserService_InsertUser(t *testing.T) {
 		result, err := s.service.InsertUser(s.ctx, PayloadInsert{
 			Name:     "rotan",
 			Phone:    "+628123456789",
-			Password: "password",
+			Password: "Sawit-Pro-2024",
+		})
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, ErrPhoneAlreadyUsed)
+	})
+
+	t.Run("invalid phone number", func(t *testing.T) {
+		s := setupService(t)
+
+		result, err := s.service.InsertUser(s.ctx, PayloadInsert{
+			Name:     "rotan",
+			Phone:    "+28123456789",
+			Password: "Sawit-Pro-2024",
+		})
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, ErrInvalidPhone)
+	})
+
+	t.Run("password violates policy", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetUserByPhone(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+		result, err := s.service.InsertUser(s.ctx, PayloadInsert{
+			Name:     "rotan",
+			Phone:    "+628123456789",
+			Password: "Rotan-2024",
 		})
 		assert.Nil(t, result)
-		assert.Equal(t, errors.NewConflictError("phone number already used"), err)
+		assert.ErrorIs(t, err, ErrPasswordPersonal)
+	})
+
+	t.Run("password too short", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetUserByPhone(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+		_, err := s.service.InsertUser(s.ctx, PayloadInsert{
+			Name:     "rotan",
+			Phone:    "+628123456789",
+			Password: "Ab1!",
+		})
+		var appErr *errors.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, CodePasswordTooShort, appErr.Code)
+		assert.Equal(t, map[string]string{"min": "6"}, appErr.Params)
+	})
+
+	t.Run("stores phone number in E.164", func(t *testing.T) {
+		s := setupService(t)
+		id := int64(1)
+		s.repository.EXPECT().GetUserByPhone(gomock.Any(), "+628123456789").Return(nil, nil)
+		s.repository.EXPECT().InsertUser(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user repository.User) (*int64, error) {
+			assert.Equal(t, "+628123456789", user.Phone)
+			return &id, nil
+		})
+
+		_, err := s.service.InsertUser(s.ctx, PayloadInsert{
+			Name:     "rotan",
+			Phone:    "0812-3456-789",
+			Password: "Sawit-Pro-2024",
+		})
+		assert.NoError(t, err)
 	})
 
 	t.Run("successfully insert user", func(t *testing.T) {
@@ -277,9 +667,546 @@ func TestUserService_InsertUser(t *testing.T) {
 		result, err := s.service.InsertUser(s.ctx, PayloadInsert{
 			Name:     "rotan",
 			Phone:    "+628123456789",
-			Password: "password",
+			Password: "Sawit-Pro-2024",
 		})
 		assert.NoError(t, err)
 		assert.Equal(t, id, *result)
 	})
+
+	t.Run("phone number taken concurrently", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetUserByPhone(gomock.Any(), gomock.Any()).Return(nil, nil)
+		s.repository.EXPECT().InsertUser(gomock.Any(), gomock.Any()).Return(nil, &repository.ConstraintError{
+			Kind:       repository.ErrUniqueViolation,
+			Constraint: repository.ConstraintUserPhone,
+		})
+
+		result, err := s.service.InsertUser(s.ctx, PayloadInsert{
+			Name:     "rotan",
+			Phone:    "+628123456789",
+			Password: "Sawit-Pro-2024",
+		})
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, ErrPhoneAlreadyUsed)
+		assert.ErrorIs(t, err, repository.ErrUniqueViolation)
+	})
+
+	t.Run("email already used", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetUserByPhone(gomock.Any(), gomock.Any()).Return(nil, nil)
+		s.repository.EXPECT().GetUserByEmail(gomock.Any(), "rotan@example.com").Return(&repository.User{Id: 2}, nil)
+
+		result, err := s.service.InsertUser(s.ctx, PayloadInsert{
+			Name:     "rotan",
+			Phone:    "+628123456789",
+			Email:    "rotan@example.com",
+			Password: "Sawit-Pro-2024",
+		})
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, ErrEmailAlreadyUsed)
+	})
+
+	t.Run("sends email verification", func(t *testing.T) {
+		s := setupService(t)
+		id := int64(1)
+		var tokenHash string
+		s.repository.EXPECT().GetUserByPhone(gomock.Any(), gomock.Any()).Return(nil, nil)
+		s.repository.EXPECT().GetUserByEmail(gomock.Any(), "rotan@example.com").Return(nil, nil)
+		s.repository.EXPECT().InsertUser(gomock.Any(), gomock.Any()).Return(&id, nil)
+		s.repository.EXPECT().InsertEmailVerification(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, payload repository.EmailVerificationPayloadInsert) error {
+			assert.Equal(t, id, payload.UserId)
+			assert.Equal(t, "rotan@example.com", payload.Email)
+			tokenHash = payload.TokenHash
+			return nil
+		})
+		s.mailer.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, message mailer.Message) error {
+			assert.Equal(t, "rotan@example.com", message.To)
+			link := message.Body[strings.Index(message.Body, "http"):]
+			u, err := url.Parse(link)
+			assert.NoError(t, err)
+			assert.Equal(t, tokenHash, hashToken(u.Query().Get("token")))
+			return nil
+		})
+
+		result, err := s.service.InsertUser(s.ctx, PayloadInsert{
+			Name:     "rotan",
+			Phone:    "+628123456789",
+			Email:    "Rotan@example.com",
+			Password: "Sawit-Pro-2024",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, id, *result)
+	})
+
+	t.Run("failing to send email verification does not fail register", func(t *testing.T) {
+		s := setupService(t)
+		id := int64(1)
+		s.repository.EXPECT().GetUserByPhone(gomock.Any(), gomock.Any()).Return(nil, nil)
+		s.repository.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Return(nil, nil)
+		s.repository.EXPECT().InsertUser(gomock.Any(), gomock.Any()).Return(&id, nil)
+		s.repository.EXPECT().InsertEmailVerification(gomock.Any(), gomock.Any()).Return(nil)
+		s.mailer.EXPECT().Send(gomock.Any(), gomock.Any()).Return(s.mockedErr)
+
+		result, err := s.service.InsertUser(s.ctx, PayloadInsert{
+			Name:     "rotan",
+			Phone:    "+628123456789",
+			Email:    "rotan@example.com",
+			Password: "Sawit-Pro-2024",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, id, *result)
+	})
+}
+
+func TestUserService_VerifyEmail(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid token", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().VerifyEmail(gomock.Any(), hashToken("token")).Return(nil, nil)
+
+		err := s.service.VerifyEmail(s.ctx, PayloadVerifyEmail{Token: "token"})
+		assert.ErrorIs(t, err, ErrInvalidEmailToken)
+	})
+
+	t.Run("successfully verify email", func(t *testing.T) {
+		s := setupService(t)
+		id := int64(1)
+		s.repository.EXPECT().VerifyEmail(gomock.Any(), hashToken("token")).Return(&id, nil)
+
+		err := s.service.VerifyEmail(s.ctx, PayloadVerifyEmail{Token: "token"})
+		assert.NoError(t, err)
+	})
+}
+
+func TestUserService_ResendEmailVerification(t *testing.T) {
+	t.Parallel()
+
+	t.Run("user has no email", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(&repository.User{Id: 1}, nil)
+
+		err := s.service.ResendEmailVerification(s.ctx, 1)
+		assert.ErrorIs(t, err, ErrEmailNotSet)
+	})
+
+	t.Run("email already verified", func(t *testing.T) {
+		s := setupService(t)
+		verifiedAt := time.Now()
+		s.repository.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(&repository.User{Id: 1, Email: "rotan@example.com", EmailVerifiedAt: &verifiedAt}, nil)
+
+		err := s.service.ResendEmailVerification(s.ctx, 1)
+		assert.ErrorIs(t, err, ErrEmailAlreadyVerified)
+	})
+
+	t.Run("successfully resend email verification", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(&repository.User{Id: 1, Email: "rotan@example.com"}, nil)
+		s.repository.EXPECT().InsertEmailVerification(gomock.Any(), gomock.Any()).Return(nil)
+		s.mailer.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil)
+
+		err := s.service.ResendEmailVerification(s.ctx, 1)
+		assert.NoError(t, err)
+	})
+}
+
+func TestUserService_ChangePassword(t *testing.T) {
+	t.Parallel()
+
+	setupUser := func(s *component) *repository.User {
+		hashedPassword, _ := s.hasher.Hash("Sawit-Pro-2024")
+		user := &repository.User{Id: 1, Name: "rotan", Phone: "+628123456789", Password: hashedPassword}
+		s.repository.EXPECT().GetUserById(gomock.Any(), int64(1)).Return(user, nil)
+		return user
+	}
+
+	t.Run("wrong current password", func(t *testing.T) {
+		s := setupService(t)
+		setupUser(s)
+
+		err := s.service.ChangePassword(s.ctx, PayloadChangePassword{Id: 1, CurrentPassword: "Sawit-Pro-2023", NewPassword: "Kebun-Sawit-99"})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("new password violates policy", func(t *testing.T) {
+		s := setupService(t)
+		setupUser(s)
+
+		err := s.service.ChangePassword(s.ctx, PayloadChangePassword{Id: 1, CurrentPassword: "Sawit-Pro-2024", NewPassword: "kebun-sawit-99"})
+		assert.ErrorIs(t, err, ErrPasswordUppercase)
+	})
+
+	t.Run("reuses current password", func(t *testing.T) {
+		s := setupService(t)
+		setupUser(s)
+		s.repository.EXPECT().GetPasswordHistory(gomock.Any(), int64(1), 2).Return(nil, nil)
+
+		err := s.service.ChangePassword(s.ctx, PayloadChangePassword{Id: 1, CurrentPassword: "Sawit-Pro-2024", NewPassword: "Sawit-Pro-2024"})
+		assert.ErrorIs(t, err, ErrPasswordReused)
+	})
+
+	t.Run("reuses a previous password", func(t *testing.T) {
+		s := setupService(t)
+		setupUser(s)
+		previous, _ := s.hasher.Hash("Kebun-Sawit-99")
+		legacy, _ := bcrypt.GenerateFromPassword([]byte("Kebun-Sawit-98"), bcrypt.MinCost)
+		s.repository.EXPECT().GetPasswordHistory(gomock.Any(), int64(1), 2).Return([]string{previous, string(legacy)}, nil)
+
+		err := s.service.ChangePassword(s.ctx, PayloadChangePassword{Id: 1, CurrentPassword: "Sawit-Pro-2024", NewPassword: "Kebun-Sawit-98"})
+		assert.ErrorIs(t, err, ErrPasswordReused)
+	})
+
+	t.Run("successfully change password", func(t *testing.T) {
+		s := setupService(t)
+		setupUser(s)
+		s.repository.EXPECT().GetPasswordHistory(gomock.Any(), int64(1), 2).Return(nil, nil)
+		s.repository.EXPECT().ChangePassword(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, payload repository.PasswordChangePayload) error {
+			assert.Equal(t, int64(1), payload.UserId)
+			assert.Equal(t, 2, payload.HistorySize)
+			assert.NoError(t, s.hasher.Compare(payload.Password, "Kebun-Sawit-99"))
+			return nil
+		})
+
+		err := s.service.ChangePassword(s.ctx, PayloadChangePassword{Id: 1, CurrentPassword: "Sawit-Pro-2024", NewPassword: "Kebun-Sawit-99"})
+		assert.NoError(t, err)
+	})
+}
+
+func TestUserService_RequestPasswordReset(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ignores unknown email", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetUserByEmail(gomock.Any(), "rotan@example.com").Return(nil, nil)
+
+		err := s.service.RequestPasswordReset(s.ctx, PayloadRequestPasswordReset{Email: "rotan@example.com"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ignores unverified email", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetUserByEmail(gomock.Any(), "rotan@example.com").Return(&repository.User{Id: 1, Email: "rotan@example.com"}, nil)
+
+		err := s.service.RequestPasswordReset(s.ctx, PayloadRequestPasswordReset{Email: "rotan@example.com"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("sends password reset link", func(t *testing.T) {
+		s := setupService(t)
+		verifiedAt := time.Now()
+		var tokenHash string
+		s.repository.EXPECT().GetUserByEmail(gomock.Any(), "rotan@example.com").Return(&repository.User{Id: 1, Email: "rotan@example.com", EmailVerifiedAt: &verifiedAt}, nil)
+		s.repository.EXPECT().InsertPasswordReset(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, payload repository.PasswordResetPayloadInsert) error {
+			assert.Equal(t, int64(1), payload.UserId)
+			tokenHash = payload.TokenHash
+			return nil
+		})
+		s.mailer.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, message mailer.Message) error {
+			assert.Equal(t, "rotan@example.com", message.To)
+			u, err := url.Parse(message.Body[strings.Index(message.Body, "http"):])
+			assert.NoError(t, err)
+			assert.Equal(t, "/reset-password", u.Path)
+			assert.Equal(t, tokenHash, hashToken(u.Query().Get("token")))
+			return nil
+		})
+
+		err := s.service.RequestPasswordReset(s.ctx, PayloadRequestPasswordReset{Email: "Rotan@example.com"})
+		assert.NoError(t, err)
+	})
+}
+
+func TestUserService_ResetPassword(t *testing.T) {
+	t.Parallel()
+	id := int64(1)
+
+	t.Run("invalid token", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetPasswordReset(gomock.Any(), hashToken("token")).Return(nil, nil)
+
+		err := s.service.ResetPassword(s.ctx, PayloadResetPassword{Token: "token", Password: "Kebun-Sawit-99"})
+		assert.ErrorIs(t, err, ErrInvalidResetToken)
+	})
+
+	t.Run("rejected password does not consume the token", func(t *testing.T) {
+		s := setupService(t)
+		current, _ := s.hasher.Hash("Sawit-Pro-2024")
+		previous, _ := s.hasher.Hash("Kebun-Sawit-99")
+		s.repository.EXPECT().GetPasswordReset(gomock.Any(), hashToken("token")).Return(&id, nil)
+		s.repository.EXPECT().GetUserById(gomock.Any(), id).Return(&repository.User{Id: id, Name: "rotan", Password: current}, nil)
+		s.repository.EXPECT().GetPasswordHistory(gomock.Any(), id, 2).Return([]string{previous}, nil)
+
+		err := s.service.ResetPassword(s.ctx, PayloadResetPassword{Token: "token", Password: "Kebun-Sawit-99"})
+		assert.ErrorIs(t, err, ErrPasswordReused)
+	})
+
+	t.Run("token used concurrently", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetPasswordReset(gomock.Any(), hashToken("token")).Return(&id, nil)
+		s.repository.EXPECT().GetUserById(gomock.Any(), id).Return(&repository.User{Id: id, Name: "rotan"}, nil)
+		s.repository.EXPECT().GetPasswordHistory(gomock.Any(), id, 2).Return(nil, nil)
+		s.repository.EXPECT().UsePasswordReset(gomock.Any(), hashToken("token")).Return(nil, nil)
+
+		err := s.service.ResetPassword(s.ctx, PayloadResetPassword{Token: "token", Password: "Kebun-Sawit-99"})
+		assert.ErrorIs(t, err, ErrInvalidResetToken)
+	})
+
+	t.Run("successfully reset password", func(t *testing.T) {
+		s := setupService(t)
+		s.repository.EXPECT().GetPasswordReset(gomock.Any(), hashToken("token")).Return(&id, nil)
+		s.repository.EXPECT().GetUserById(gomock.Any(), id).Return(&repository.User{Id: id, Name: "rotan"}, nil)
+		s.repository.EXPECT().GetPasswordHistory(gomock.Any(), id, 2).Return(nil, nil)
+		s.repository.EXPECT().UsePasswordReset(gomock.Any(), hashToken("token")).Return(&id, nil)
+		s.repository.EXPECT().ChangePassword(gomock.Any(), gomock.Any()).Return(nil)
+
+		err := s.service.ResetPassword(s.ctx, PayloadResetPassword{Token: "token", Password: "Kebun-Sawit-99"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("token and password are written in one transaction", func(t *testing.T) {
+		g := gomock.NewController(t)
+		repo := repository.NewMockRepositoryInterface(g)
+		tx := repository.NewMockRepositoryInterface(g)
+		s := NewService(NewServiceOption{
+			UserRepository:      repo,
+			PasswordHasher:      password.NewHasher(password.DefaultOptions),
+			PasswordHistorySize: 2,
+		})
+		repo.EXPECT().GetPasswordReset(gomock.Any(), hashToken("token")).Return(&id, nil)
+		repo.EXPECT().GetUserById(gomock.Any(), id).Return(&repository.User{Id: id, Name: "rotan"}, nil)
+		repo.EXPECT().GetPasswordHistory(gomock.Any(), id, 2).Return(nil, nil)
+		repo.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, fn func(repository.RepositoryInterface) error) error {
+				return fn(tx)
+			})
+		tx.EXPECT().UsePasswordReset(gomock.Any(), hashToken("token")).Return(&id, nil)
+		tx.EXPECT().ChangePassword(gomock.Any(), gomock.Any()).Return(nil)
+
+		err := s.ResetPassword(context.Background(), PayloadResetPassword{Token: "token", Password: "Kebun-Sawit-99"})
+		assert.NoError(t, err)
+	})
+}
+
+func TestUserService_UploadAvatar(t *testing.T) {
+	t.Parallel()
+
+	var picture bytes.Buffer
+	png.Encode(&picture, image.NewGray(image.Rect(0, 0, 80, 60)))
+
+	t.Run("successfully upload avatar", func(t *testing.T) {
+		s := setupService(t)
+		var key string
+		s.avatarStore.EXPECT().Put(gomock.Any(), gomock.Any(), gomock.Any(), "image/jpeg").Times(3).Return(nil)
+		s.repository.EXPECT().UpdateAvatar(gomock.Any(), int64(1), gomock.Any()).DoAndReturn(func(_ context.Context, _ int64, k string) (*repository.AvatarUpdated, error) {
+			key = k
+			assert.True(t, strings.HasPrefix(k, "1/"))
+			return &repository.AvatarUpdated{PreviousKey: "1/old", Version: 4}, nil
+		})
+		s.avatarStore.EXPECT().Delete(gomock.Any(), "1/old-small.jpg").Return(nil)
+		s.avatarStore.EXPECT().Delete(gomock.Any(), "1/old-medium.jpg").Return(nil)
+		s.avatarStore.EXPECT().Delete(gomock.Any(), "1/old-large.jpg").Return(s.mockedErr)
+		s.avatarStore.EXPECT().URL(gomock.Any()).Times(3).DoAndReturn(func(k string) string {
+			return "http://localhost/v1/avatars/" + k
+		})
+
+		result, err := s.service.UploadAvatar(s.ctx, PayloadUploadAvatar{Id: 1, File: bytes.NewReader(picture.Bytes())})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), result.Version)
+		assert.Equal(t, map[string]string{
+			"small":  "http://localhost/v1/avatars/" + key + "-small.jpg",
+			"medium": "http://localhost/v1/avatars/" + key + "-medium.jpg",
+			"large":  "http://localhost/v1/avatars/" + key + "-large.jpg",
+		}, result.Avatars)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		s := setupService(t)
+
+		_, err := s.service.UploadAvatar(s.ctx, PayloadUploadAvatar{
+			Id:   1,
+			File: strings.NewReader(strings.Repeat("a", DefaultAvatarMaxSize+1)),
+		})
+		var appErr *errors.Error
+		assert.ErrorAs(t, err, &appErr)
+		assert.Equal(t, CodeAvatarTooLarge, appErr.Code)
+		assert.Equal(t, map[string]string{"max": "5 MiB"}, appErr.Params)
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		s := setupService(t)
+
+		_, err := s.service.UploadAvatar(s.ctx, PayloadUploadAvatar{Id: 1, File: strings.NewReader("%PDF-1.4")})
+		assert.ErrorIs(t, err, ErrAvatarType)
+	})
+
+	t.Run("invalid image", func(t *testing.T) {
+		s := setupService(t)
+
+		_, err := s.service.UploadAvatar(s.ctx, PayloadUploadAvatar{Id: 1, File: bytes.NewReader(picture.Bytes()[:40])})
+		assert.ErrorIs(t, err, ErrInvalidAvatar)
+	})
+
+	t.Run("new thumbnails are removed when the update fails", func(t *testing.T) {
+		s := setupService(t)
+		s.avatarStore.EXPECT().Put(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3).Return(nil)
+		s.repository.EXPECT().UpdateAvatar(gomock.Any(), int64(1), gomock.Any()).Return(nil, s.mockedErr)
+		s.avatarStore.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(3).Return(nil)
+
+		_, err := s.service.UploadAvatar(s.ctx, PayloadUploadAvatar{Id: 1, File: bytes.NewReader(picture.Bytes())})
+		assert.Equal(t, s.mockedErr, err)
+	})
+
+	t.Run("store failure", func(t *testing.T) {
+		s := setupService(t)
+		s.avatarStore.EXPECT().Put(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(s.mockedErr)
+		s.avatarStore.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(3).Return(nil)
+
+		_, err := s.service.UploadAvatar(s.ctx, PayloadUploadAvatar{Id: 1, File: bytes.NewReader(picture.Bytes())})
+		assert.Equal(t, s.mockedErr, err)
+	})
+}
+
+func TestUserService_GetAvatar(t *testing.T) {
+	t.Parallel()
+
+	t.Run("successfully get avatar", func(t *testing.T) {
+		s := setupService(t)
+		object := &blob.Object{ContentType: "image/jpeg"}
+		s.avatarStore.EXPECT().Get(gomock.Any(), "1/abc-small.jpg").Return(object, nil)
+
+		result, err := s.service.GetAvatar(s.ctx, 1, "abc-small.jpg")
+		assert.NoError(t, err)
+		assert.Equal(t, object, result)
+	})
+
+	t.Run("avatar not found", func(t *testing.T) {
+		s := setupService(t)
+		s.avatarStore.EXPECT().Get(gomock.Any(), "1/abc-small.jpg").Return(nil, blob.ErrNotFound)
+
+		_, err := s.service.GetAvatar(s.ctx, 1, "abc-small.jpg")
+		assert.ErrorIs(t, err, ErrAvatarNotFound)
+	})
+
+	t.Run("file cannot be a path", func(t *testing.T) {
+		s := setupService(t)
+
+		_, err := s.service.GetAvatar(s.ctx, 1, "../2/abc-small.jpg")
+		assert.ErrorIs(t, err, ErrAvatarNotFound)
+	})
+}
+
+// TestUserService_MemoryRepository runs whole flows against the in-memory
+// repository instead of scripting every call.
+func TestPolicyError(t *testing.T) {
+	t.Parallel()
+
+	tests := map[password.Rule]error{
+		password.RuleMaxLength:    ErrPasswordTooLong,
+		password.RuleUppercase:    ErrPasswordUppercase,
+		password.RuleLowercase:    ErrPasswordLowercase,
+		password.RuleDigit:        ErrPasswordDigit,
+		password.RuleSpecial:      ErrPasswordSpecial,
+		password.RulePersonalInfo: ErrPasswordPersonal,
+		password.RuleBreached:     ErrPasswordBreached,
+		password.Rule("unknown"):  ErrPasswordPolicy,
+	}
+	for rule, want := range tests {
+		t.Run(string(rule), func(t *testing.T) {
+			err := policyError(&password.PolicyError{Rule: rule})
+			assert.ErrorIs(t, err, want)
+			if rule != password.RuleBreached {
+				assert.NotErrorIs(t, err, ErrPasswordBreached)
+			}
+		})
+	}
+
+	t.Run("min length", func(t *testing.T) {
+		err := policyError(&password.PolicyError{Rule: password.RuleMinLength, Param: "8"})
+		assert.ErrorIs(t, err, ErrPasswordTooShort)
+		assert.EqualError(t, err, "password must be at least 8 characters")
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		assert.NoError(t, policyError(nil))
+		assert.Equal(t, assert.AnError, policyError(assert.AnError))
+	})
+}
+
+func TestUserService_MemoryRepository(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s := NewService(NewServiceOption{
+		UserRepository:      repository.NewMemoryRepository(repository.NewMemoryRepositoryOptions{}),
+		TokenService:        jwt.NewTokenService(jwt.Options{PrivateKey: func() string { return "test-key" }}),
+		PasswordHasher:      password.NewBcrypt(password.BcryptParams{Cost: bcrypt.MinCost}),
+		PasswordHistorySize: 2,
+	})
+
+	id, err := s.InsertUser(ctx, PayloadInsert{Name: "rotan", Phone: "+628123456789", Password: "Sawit-Pro-2024"})
+	assert.NoError(t, err)
+
+	t.Run("phone already used", func(t *testing.T) {
+		_, err := s.InsertUser(ctx, PayloadInsert{Name: "kebun", Phone: "+628123456789", Password: "Sawit-Pro-2024"})
+		assert.ErrorIs(t, err, ErrPhoneAlreadyUsed)
+	})
+
+	t.Run("login", func(t *testing.T) {
+		result, err := s.Login(ctx, PayloadLogin{Phone: "+628123456789", Password: "Sawit-Pro-2024"})
+		assert.NoError(t, err)
+		assert.Equal(t, *id, result.UserId)
+
+		_, err = s.Login(ctx, PayloadLogin{Phone: "+628123456789", Password: "Kebun-Sawit-99"})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("update profile with a stale version", func(t *testing.T) {
+		version, err := s.UpdateProfile(ctx, PayloadUpdate{Id: *id, Versions: []int64{1}, Name: patch.Value("kebun")})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), version)
+
+		_, err = s.UpdateProfile(ctx, PayloadUpdate{Id: *id, Versions: []int64{1}, Name: patch.Value("sawit")})
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+
+		user, err := s.GetByID(ctx, *id)
+		assert.NoError(t, err)
+		assert.Equal(t, "kebun", user.Name)
+	})
+
+	t.Run("change password rejects reuse", func(t *testing.T) {
+		err := s.ChangePassword(ctx, PayloadChangePassword{Id: *id, CurrentPassword: "Sawit-Pro-2024", NewPassword: "Mangga-Manis-88"})
+		assert.NoError(t, err)
+
+		err = s.ChangePassword(ctx, PayloadChangePassword{Id: *id, CurrentPassword: "Mangga-Manis-88", NewPassword: "Sawit-Pro-2024"})
+		assert.ErrorIs(t, err, ErrPasswordReused)
+
+		_, err = s.Login(ctx, PayloadLogin{Phone: "+628123456789", Password: "Mangga-Manis-88"})
+		assert.NoError(t, err)
+	})
+}
+
+// TestUserService_CachedRepository checks that flows needing the password hash
+// are not answered by the user cache, which does not keep it.
+func TestUserService_CachedRepository(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	s := NewService(NewServiceOption{
+		UserRepository: repository.NewCachedRepository(repository.NewCachedRepositoryOptions{
+			Repository: repository.NewMemoryRepository(repository.NewMemoryRepositoryOptions{}),
+			Cache:      cache.NewLRU(cache.LRUOptions{Size: 10}),
+		}),
+		TokenService:   jwt.NewTokenService(jwt.Options{PrivateKey: func() string { return "test-key" }}),
+		PasswordHasher: password.NewBcrypt(password.BcryptParams{Cost: bcrypt.MinCost}),
+	})
+	id, err := s.InsertUser(ctx, PayloadInsert{Name: "rotan", Phone: "+628123456789", Password: "Sawit-Pro-2024"})
+	require.NoError(t, err)
+
+	// Cache the user by id and by phone.
+	_, err = s.GetByID(ctx, *id)
+	require.NoError(t, err)
+	_, err = s.Login(ctx, PayloadLogin{Phone: "+628123456789", Password: "Sawit-Pro-2024"})
+	require.NoError(t, err)
+	_, err = s.Login(ctx, PayloadLogin{Phone: "+628123456789", Password: "Sawit-Pro-2024"})
+	assert.NoError(t, err)
+
+	err = s.ChangePassword(ctx, PayloadChangePassword{Id: *id, CurrentPassword: "Sawit-Pro-2024", NewPassword: "Mangga-Manis-88"})
+	assert.NoError(t, err)
 }
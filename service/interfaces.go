@@ -1,11 +1,22 @@
 package service
 
-import "context"
+import (
+	"context"
+
+	"github.com/SawitProRecruitment/UserService/lib/blob"
+)
 
 //go:generate mockgen -source interfaces.go -destination interfaces.mock.gen.go -package=service
 type ServiceInterface interface {
 	GetByID(ctx context.Context, id int64) (*User, error)
 	Login(ctx context.Context, payload PayloadLogin) (*ResponseLogin, error)
-	UpdateProfile(ctx context.Context, payload PayloadUpdate) error
+	UpdateProfile(ctx context.Context, payload PayloadUpdate) (int64, error)
 	InsertUser(ctx context.Context, payload PayloadInsert) (*int64, error)
+	VerifyEmail(ctx context.Context, payload PayloadVerifyEmail) error
+	ResendEmailVerification(ctx context.Context, id int64) error
+	ChangePassword(ctx context.Context, payload PayloadChangePassword) error
+	RequestPasswordReset(ctx context.Context, payload PayloadRequestPasswordReset) error
+	ResetPassword(ctx context.Context, payload PayloadResetPassword) error
+	UploadAvatar(ctx context.Context, payload PayloadUploadAvatar) (*ResponseUploadAvatar, error)
+	GetAvatar(ctx context.Context, userId int64, file string) (*blob.Object, error)
 }
@@ -8,6 +8,7 @@ import (
 	context "context"
 	reflect "reflect"
 
+	blob "github.com/SawitProRecruitment/UserService/lib/blob"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -34,6 +35,35 @@ func (m *MockServiceInterface) EXPECT() *MockServiceInterfaceMockRecorder {
 	return m.recorder
 }
 
+// ChangePassword mocks base method.
+func (m *MockServiceInterface) ChangePassword(ctx context.Context, payload PayloadChangePassword) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockServiceInterfaceMockRecorder) ChangePassword(ctx, payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockServiceInterface)(nil).ChangePassword), ctx, payload)
+}
+
+// GetAvatar mocks base method.
+func (m *MockServiceInterface) GetAvatar(ctx context.Context, userId int64, file string) (*blob.Object, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAvatar", ctx, userId, file)
+	ret0, _ := ret[0].(*blob.Object)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAvatar indicates an expected call of GetAvatar.
+func (mr *MockServiceInterfaceMockRecorder) GetAvatar(ctx, userId, file interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAvatar", reflect.TypeOf((*MockServiceInterface)(nil).GetAvatar), ctx, userId, file)
+}
+
 // GetByID mocks base method.
 func (m *MockServiceInterface) GetByID(ctx context.Context, id int64) (*User, error) {
 	m.ctrl.T.Helper()
@@ -79,16 +109,88 @@ func (mr *MockServiceInterfaceMockRecorder) Login(ctx, payload interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockServiceInterface)(nil).Login), ctx, payload)
 }
 
-// UpdateProfile mocks base method.
-func (m *MockServiceInterface) UpdateProfile(ctx context.Context, payload PayloadUpdate) error {
+// RequestPasswordReset mocks base method.
+func (m *MockServiceInterface) RequestPasswordReset(ctx context.Context, payload PayloadRequestPasswordReset) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateProfile", ctx, payload)
+	ret := m.ctrl.Call(m, "RequestPasswordReset", ctx, payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequestPasswordReset indicates an expected call of RequestPasswordReset.
+func (mr *MockServiceInterfaceMockRecorder) RequestPasswordReset(ctx, payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestPasswordReset", reflect.TypeOf((*MockServiceInterface)(nil).RequestPasswordReset), ctx, payload)
+}
+
+// ResendEmailVerification mocks base method.
+func (m *MockServiceInterface) ResendEmailVerification(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResendEmailVerification", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResendEmailVerification indicates an expected call of ResendEmailVerification.
+func (mr *MockServiceInterfaceMockRecorder) ResendEmailVerification(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendEmailVerification", reflect.TypeOf((*MockServiceInterface)(nil).ResendEmailVerification), ctx, id)
+}
+
+// ResetPassword mocks base method.
+func (m *MockServiceInterface) ResetPassword(ctx context.Context, payload PayloadResetPassword) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, payload)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockServiceInterfaceMockRecorder) ResetPassword(ctx, payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockServiceInterface)(nil).ResetPassword), ctx, payload)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockServiceInterface) UpdateProfile(ctx context.Context, payload PayloadUpdate) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, payload)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // UpdateProfile indicates an expected call of UpdateProfile.
 func (mr *MockServiceInterfaceMockRecorder) UpdateProfile(ctx, payload interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockServiceInterface)(nil).UpdateProfile), ctx, payload)
 }
+
+// UploadAvatar mocks base method.
+func (m *MockServiceInterface) UploadAvatar(ctx context.Context, payload PayloadUploadAvatar) (*ResponseUploadAvatar, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadAvatar", ctx, payload)
+	ret0, _ := ret[0].(*ResponseUploadAvatar)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadAvatar indicates an expected call of UploadAvatar.
+func (mr *MockServiceInterfaceMockRecorder) UploadAvatar(ctx, payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadAvatar", reflect.TypeOf((*MockServiceInterface)(nil).UploadAvatar), ctx, payload)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockServiceInterface) VerifyEmail(ctx context.Context, payload PayloadVerifyEmail) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockServiceInterfaceMockRecorder) VerifyEmail(ctx, payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockServiceInterface)(nil).VerifyEmail), ctx, payload)
+}
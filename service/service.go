@@ -1,20 +1,93 @@
 package service
 
 import (
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/lib/blob"
+	"github.com/SawitProRecruitment/UserService/lib/jwt"
+	"github.com/SawitProRecruitment/UserService/lib/mailer"
+	"github.com/SawitProRecruitment/UserService/lib/password"
+	"github.com/SawitProRecruitment/UserService/lib/phone"
 	"github.com/SawitProRecruitment/UserService/repository"
 	_ "github.com/lib/pq"
 )
 
+const (
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+
+	// DefaultAvatarMaxSize is the upload limit in bytes.
+	DefaultAvatarMaxSize = 5 << 20
+)
+
 type service struct {
-	userRepository repository.RepositoryInterface
+	userRepository       repository.RepositoryInterface
+	tokenService         jwt.TokenService
+	phoneParser          *phone.Parser
+	passwordHasher       password.PasswordHasher
+	passwordPolicy       *password.Policy
+	passwordHistorySize  int
+	mailer               mailer.Mailer
+	emailVerificationURL string
+	passwordResetURL     string
+	avatarStore          blob.BlobStore
+	avatarMaxSize        int64
 }
 
 type NewServiceOption struct {
 	UserRepository repository.RepositoryInterface
+	// TokenService signs the tokens of Login.
+	TokenService jwt.TokenService
+	// PhoneParser defaults to only accepting Indonesian numbers.
+	PhoneParser *phone.Parser
+	// PasswordHasher defaults to Argon2id with the OWASP recommended parameters.
+	PasswordHasher password.PasswordHasher
+	// PasswordPolicy defaults to password.DefaultPolicyOptions.
+	PasswordPolicy *password.Policy
+	// PasswordHistorySize is the number of previous passwords that cannot be
+	// reused, on top of the current one.
+	PasswordHistorySize int
+	// Mailer defaults to logging messages instead of sending them.
+	Mailer mailer.Mailer
+	// EmailVerificationURL is the page the verification link points to, the
+	// token is appended as the `token` query parameter.
+	EmailVerificationURL string
+	// PasswordResetURL is the page the password reset link points to, the
+	// token is appended as the `token` query parameter.
+	PasswordResetURL string
+	// AvatarStore keeps the avatar thumbnails, uploads fail without one.
+	AvatarStore blob.BlobStore
+	// AvatarMaxSize in bytes, defaults to DefaultAvatarMaxSize.
+	AvatarMaxSize int64
 }
 
 func NewService(opts NewServiceOption) ServiceInterface {
+	if opts.PhoneParser == nil {
+		opts.PhoneParser = phone.NewParser(phone.DefaultOptions)
+	}
+	if opts.PasswordHasher == nil {
+		opts.PasswordHasher = password.NewHasher(password.DefaultOptions)
+	}
+	if opts.PasswordPolicy == nil {
+		opts.PasswordPolicy = password.NewPolicy(password.DefaultPolicyOptions)
+	}
+	if opts.Mailer == nil {
+		opts.Mailer = mailer.NewLogMailer()
+	}
+	if opts.AvatarMaxSize <= 0 {
+		opts.AvatarMaxSize = DefaultAvatarMaxSize
+	}
 	return &service{
-		userRepository: opts.UserRepository,
+		userRepository:       opts.UserRepository,
+		tokenService:         opts.TokenService,
+		phoneParser:          opts.PhoneParser,
+		passwordHasher:       opts.PasswordHasher,
+		passwordPolicy:       opts.PasswordPolicy,
+		passwordHistorySize:  opts.PasswordHistorySize,
+		mailer:               opts.Mailer,
+		emailVerificationURL: opts.EmailVerificationURL,
+		passwordResetURL:     opts.PasswordResetURL,
+		avatarStore:          opts.AvatarStore,
+		avatarMaxSize:        opts.AvatarMaxSize,
 	}
 }
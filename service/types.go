@@ -1,38 +1,103 @@
 package service
 
-import "github.com/SawitProRecruitment/UserService/repository"
+import (
+	"io"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/lib/patch"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
 
 type PayloadInsert struct {
-	Name     string `json:"name" validate:"required,min=3,max=60"`
-	Phone    string `json:"phone" validate:"required,customPhone"`
-	Password string `json:"password" validate:"required,customPassword"`
+	Name  string `json:"name" validate:"required,min=3,max=60"`
+	Phone string `json:"phone" validate:"required,customPhone"`
+	Email string `json:"email,omitempty" validate:"omitempty,email,max=254"`
+	// Password is checked against the password policy by the service.
+	Password string `json:"password" validate:"required"`
 }
 
+// PayloadLogin identifies the user by either phone or a verified email.
 type PayloadLogin struct {
-	Phone    string `json:"phone" validate:"required,customPhone"`
-	Password string `json:"password" validate:"required,customPassword"`
+	Phone    string `json:"phone,omitempty" validate:"required_without=Email,omitempty,customPhone"`
+	Email    string `json:"email,omitempty" validate:"required_without=Phone,omitempty,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type PayloadVerifyEmail struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type PayloadChangePassword struct {
+	Id              int64  `json:"-"`
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type PayloadRequestPasswordReset struct {
+	Email string `json:"email" validate:"required,email"`
 }
 
+type PayloadResetPassword struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// PayloadUpdate is a JSON Merge Patch of the profile: absent members are left
+// unchanged and null removes optional ones. When Versions is not empty the
+// patch is only applied if the user is still at one of those versions.
 type PayloadUpdate struct {
-	Id    int64
-	Name  string `json:"name" validate:"required,min=3,max=60"`
-	Phone string `json:"phone" validate:"required,customPhone"`
+	Id          int64               `json:"-"`
+	Versions    []int64             `json:"-"`
+	Name        patch.Field[string] `json:"name" validate:"omitempty,min=3,max=60"`
+	Phone       patch.Field[string] `json:"phone" validate:"omitempty,customPhone"`
+	Email       patch.Field[string] `json:"email" validate:"omitempty,email,max=254"`
+	DateOfBirth patch.Field[string] `json:"date_of_birth" validate:"omitempty,customDateOfBirth"`
+	AvatarURL   patch.Field[string] `json:"avatar_url" validate:"omitempty,url,max=2048"`
+	Locale      patch.Field[string] `json:"locale" validate:"omitempty,customLocale"`
+	Timezone    patch.Field[string] `json:"timezone" validate:"omitempty,customTimezone"`
 }
 
 type User struct {
-	Id    int64
-	Name  string
-	Phone string
+	Id            int64
+	Name          string
+	Phone         string
+	Email         string
+	EmailVerified bool
+	DateOfBirth   *time.Time
+	AvatarURL     string
+	Locale        string
+	Timezone      string
+	Version       int64
+	// Avatars maps the thumbnail sizes of the uploaded avatar to their URL.
+	Avatars map[string]string
 }
 
 func ParseUser(userRepo *repository.User) *User {
 	return &User{
-		Id:    userRepo.Id,
-		Name:  userRepo.Name,
-		Phone: userRepo.Phone,
+		Id:            userRepo.Id,
+		Name:          userRepo.Name,
+		Phone:         userRepo.Phone,
+		Email:         userRepo.Email,
+		EmailVerified: userRepo.EmailVerifiedAt != nil,
+		DateOfBirth:   userRepo.DateOfBirth,
+		AvatarURL:     userRepo.AvatarURL,
+		Locale:        userRepo.Locale,
+		Timezone:      userRepo.Timezone,
+		Version:       userRepo.Version,
 	}
 }
 
+// PayloadUploadAvatar is an uploaded picture in any of avatar.ContentTypes.
+type PayloadUploadAvatar struct {
+	Id   int64
+	File io.Reader
+}
+
+type ResponseUploadAvatar struct {
+	Version int64
+	Avatars map[string]string
+}
+
 type ResponseLogin struct {
 	UserId int64  `json:"user_id"`
 	Token  string `json:"token"`